@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"golang.org/x/term"
+
+	"github.com/tjblackheart/andcli/v2/internal/config"
+	"github.com/tjblackheart/andcli/v2/internal/input"
+	"github.com/tjblackheart/andcli/v2/internal/output"
+	"github.com/tjblackheart/andcli/v2/internal/qr"
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/aegis"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/keepass"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/otpauth"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/twofas"
+)
+
+var errAborted = errors.New("aborted")
+
+// Adds, edits or removes entries of the first vault and saves it. The
+// previous file is kept as backup.
+func modify(cfg *config.Config) error {
+	src := cfg.Sources()[0]
+
+	// fail before asking for a password.
+	var added []vaults.Entry
+	if cfg.Command() == config.ADD {
+		var err error
+		if added, err = readEntries(cfg.URI()); err != nil {
+			return err
+		}
+	}
+
+	vault, err := open(src, cfg, "Password: ")
+	if err != nil {
+		return err
+	}
+
+	w, ok := vault.(vaults.Writable)
+	if !ok {
+		return fmt.Errorf("vault type %q: not writable", src.Type)
+	}
+
+	var msg string
+	switch cfg.Command() {
+	case config.ADD:
+		names := make([]string, 0, len(added))
+		for _, e := range added {
+			if err := w.Add(e); err != nil {
+				return fmt.Errorf("%s: %w", e.QualifiedName(), err)
+			}
+			names = append(names, e.QualifiedName())
+		}
+		msg = fmt.Sprintf("Added %s", strings.Join(names, ", "))
+	default:
+		entries := w.Entries()
+
+		i, err := query(cfg, entries)
+		if err != nil {
+			return err
+		}
+		e := entries[i]
+
+		if cfg.Command() == config.REMOVE {
+			if !cfg.Yes() {
+				ok, err := input.Confirm(fmt.Sprintf("Remove %s?", e.QualifiedName()))
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return errAborted
+				}
+			}
+
+			if err := w.Remove(i); err != nil {
+				return err
+			}
+			msg = fmt.Sprintf("Removed %s", e.QualifiedName())
+			break
+		}
+
+		changes := cfg.Changes()
+		if changes.Issuer != nil {
+			e.Issuer = *changes.Issuer
+		}
+		if changes.Label != nil {
+			e.Label = *changes.Label
+		}
+		if changes.Tags != nil {
+			e.Tags = changes.Tags
+		}
+
+		if err := w.Update(i, e); err != nil {
+			return err
+		}
+		msg = fmt.Sprintf("Updated %s", e.QualifiedName())
+	}
+
+	if err := w.Save(); err != nil {
+		return err
+	}
+
+	log.Printf("%s, previous file saved as %s.bak", msg, src.Name())
+	return nil
+}
+
+// Returns the entries of an otpauth:// or otpauth-migration:// URI. Any
+// other argument is read as image file with a QR code of such a URI.
+func readEntries(s string) ([]vaults.Entry, error) {
+	if strings.HasPrefix(strings.ToLower(s), "otpauth") {
+		return vaults.ParseURIs(s)
+	}
+
+	uri, err := qr.DecodeFile(s)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := vaults.ParseURIs(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s, err)
+	}
+
+	return entries, nil
+}
+
+// Prints the current tokens of the entries in the QR code images. Nothing
+// is stored.
+func scan(cfg *config.Config) error {
+	return scanImages(os.Stdout, cfg.Images(), cfg.Output())
+}
+
+// Writes the current tokens of the entries in the images to w.
+func scanImages(w io.Writer, images []string, f output.Format) error {
+	for _, image := range images {
+		entries, err := readEntries(image)
+		if err != nil {
+			return err
+		}
+
+		// invalid entries are logged and skipped, like in vaults.
+		for _, e := range entries {
+			if err := e.SanitizeAndValidate(); err != nil {
+				continue
+			}
+			if err := output.Write(w, e, f, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// export implementations by type.
+var exporters = map[vaults.Type]func([]vaults.Entry, []byte) ([]byte, error){
+	vaults.AEGIS:   aegis.Export,
+	vaults.TWOFAS:  twofas.Export,
+	vaults.OTPAUTH: otpauth.Export,
+	vaults.KEEPASS: keepass.Export,
+}
+
+// Writes the entries in the export format to the target file or stdout.
+// All formats but the otpauth list are encrypted with a new password. An
+// existing file is never overwritten.
+func export(cfg *config.Config, entries []vaults.Entry) error {
+	exportVault, ok := exporters[cfg.ExportTo()]
+	if !ok {
+		return fmt.Errorf("export: format %q: not supported", cfg.ExportTo())
+	}
+
+	if cfg.ExportTo() == vaults.KEEPASS && cfg.ExportOut() == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("export: not writing a binary file to the terminal, use --out")
+	}
+
+	// fail before asking for a password.
+	if _, err := os.Stat(cfg.ExportOut()); cfg.ExportOut() != "" && err == nil {
+		return fmt.Errorf("export: %s: %w", cfg.ExportOut(), os.ErrExist)
+	}
+
+	var pw []byte
+	if cfg.ExportTo() != vaults.OTPAUTH {
+		var err error
+		if pw, err = newPassword(cfg.PasswdStdin()); err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+	}
+
+	defer func() {
+		for i := range pw {
+			pw[i] = 0
+		}
+	}()
+
+	b, err := exportVault(entries, pw)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	if cfg.ExportOut() == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	// the export contains all secrets.
+	f, err := os.OpenFile(cfg.ExportOut(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	log.Printf("Exported %d entries to %s", len(entries), cfg.ExportOut())
+	return f.Close()
+}
+
+// Prints the otpauth URI of the entry as QR code. It reveals the secret,
+// so it is printed to a terminal and after a confirmation only.
+func showQR(e vaults.Entry) error {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("qr: stdout is not a terminal")
+	}
+
+	ok, err := input.Confirm(fmt.Sprintf("Show the QR code of %s? It contains the secret.", e.QualifiedName()))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errAborted
+	}
+
+	code, err := qr.Render(e.URI())
+	if err != nil {
+		return fmt.Errorf("qr: %w", err)
+	}
+
+	_, err = lipgloss.Println(qr.Style.Render(code))
+	return err
+}
+
+// Asks for the password of an export twice, or reads it once from stdin.
+func newPassword(piped bool) ([]byte, error) {
+	if piped {
+		log.Printf("Reading export password from stdin ...")
+		pw, err := input.Stdin()
+		if err == nil && len(pw) == 0 {
+			err = errors.New("empty password")
+		}
+		return pw, err
+	}
+
+	pw, err := input.Hidden("New password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pw) == 0 {
+		return nil, errors.New("empty password")
+	}
+
+	repeated, err := input.Hidden("Repeat password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(pw, repeated) {
+		return nil, errors.New("passwords do not match")
+	}
+
+	return pw, nil
+}
+
+// Prints the config file or its path.
+func printConfig(cfg *config.Config) error {
+	if cfg.PathOnly() {
+		fmt.Println(cfg.Path())
+		return nil
+	}
+
+	b, err := os.ReadFile(cfg.Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s: no config file yet, open a vault first", cfg.Path())
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(b)
+	return err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/tjblackheart/andcli/v2/internal/output"
+)
+
+func TestScanImages(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"valid", "otpauth://totp/Valid:me?secret=jbsw%20y3dp", "Valid "},
+		{"skips invalid base32", "otpauth://totp/Invalid:me?secret=not-base32!", ""},
+		{"skips invalid length", "otpauth://totp/Short:me?secret=JBSWY3", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, "code.png")
+			writeCode(t, filename, tt.uri)
+
+			var buf bytes.Buffer
+			if err := scanImages(&buf, []string{filename}, output.TEXT); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); !strings.HasPrefix(got, tt.want) || (tt.want == "" && got != "") {
+				t.Errorf("scanImages() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Writes a PNG image of the QR code of s.
+func writeCode(t *testing.T, filename, s string) {
+	t.Helper()
+
+	m, err := qrcode.NewQRCodeWriter().Encode(s, gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewGray(m.Bounds())
+	for y := range m.GetHeight() {
+		for x := range m.GetWidth() {
+			if !m.Get(x, y) {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filename, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tjblackheart/andcli/v2/internal/clipboard"
+	"github.com/tjblackheart/andcli/v2/internal/config"
+)
+
+var errChecksFailed = errors.New("doctor: some checks failed")
+
+// Checks the config file, all vault files and the clipboard command and
+// prints the result of each check. A missing clipboard command is only a
+// warning, as it is not needed for queries.
+func doctor(cfg *config.Config) error {
+	failed := false
+	report := func(name, info string, err error) {
+		if err != nil {
+			fmt.Printf("✕ %s: %s\n", name, err)
+			failed = true
+			return
+		}
+		fmt.Printf("✓ %s: %s\n", name, info)
+	}
+
+	// the config file itself has been parsed already.
+	info := cfg.Path()
+	if _, err := os.Stat(cfg.Path()); errors.Is(err, os.ErrNotExist) {
+		info += " (not created yet)"
+	}
+	report("config", info, nil)
+
+	if cfg.File == "" {
+		report("vaults", "", errors.New("no vault file specified"))
+	} else {
+		for _, src := range cfg.Sources() {
+			info, err := checkSource(src)
+			report(src.Name(), info, err)
+		}
+	}
+
+	cb := clipboard.New(cfg.ClipboardCmd)
+	if cb.IsInitialized() {
+		report("clipboard", cb.String(), nil)
+	} else {
+		fmt.Println("! clipboard: no clipboard command available, copying tokens is disabled")
+	}
+
+	if failed {
+		return errChecksFailed
+	}
+
+	return nil
+}
+
+// Validates the vault file and returns its type and encryption state.
+func checkSource(src config.Source) (string, error) {
+	if err := src.Validate(); err != nil {
+		return "", err
+	}
+
+	backend, ok := backends[src.Type]
+	if !ok {
+		return "", fmt.Errorf("vault type %q: not implemented", src.Type)
+	}
+
+	encrypted, err := backend.isEncrypted(src.File)
+	if err != nil {
+		return "", err
+	}
+
+	info := fmt.Sprintf("%s, encrypted", src.Type)
+	if !encrypted {
+		info = fmt.Sprintf("%s, not encrypted (contains all secrets in plain text)", src.Type)
+	}
+
+	if src.KeyFile != "" {
+		info += fmt.Sprintf(", key file %s", filepath.Base(src.KeyFile))
+	}
+
+	return info, nil
+}
@@ -4,24 +4,50 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"golang.org/x/term"
 
 	"github.com/tjblackheart/andcli/v2/internal/buildinfo"
 	"github.com/tjblackheart/andcli/v2/internal/config"
 	"github.com/tjblackheart/andcli/v2/internal/input"
 	"github.com/tjblackheart/andcli/v2/internal/model"
+	"github.com/tjblackheart/andcli/v2/internal/output"
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 	"github.com/tjblackheart/andcli/v2/internal/vaults/aegis"
 	"github.com/tjblackheart/andcli/v2/internal/vaults/andotp"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/bitwarden"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/ente"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/freeotp"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/freeotpplus"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/googleauth"
 	"github.com/tjblackheart/andcli/v2/internal/vaults/keepass"
+	"github.com/tjblackheart/andcli/v2/internal/vaults/otpauth"
 	"github.com/tjblackheart/andcli/v2/internal/vaults/protonpass"
 	"github.com/tjblackheart/andcli/v2/internal/vaults/stratum"
 	"github.com/tjblackheart/andcli/v2/internal/vaults/twofas"
 )
 
+// vault implementations by type.
+var backends = map[vaults.Type]struct {
+	open        func(string, []byte) (vaults.Vault, error)
+	isEncrypted func(string) (bool, error)
+}{
+	vaults.ANDOTP:      {andotp.Open, andotp.IsEncrypted},
+	vaults.AEGIS:       {aegis.Open, aegis.IsEncrypted},
+	vaults.TWOFAS:      {twofas.Open, twofas.IsEncrypted},
+	vaults.STRATUM:     {stratum.Open, stratum.IsEncrypted},
+	vaults.KEEPASS:     {keepass.Open, keepass.IsEncrypted},
+	vaults.PROTON:      {protonpass.Open, protonpass.IsEncrypted},
+	vaults.OTPAUTH:     {otpauth.Open, otpauth.IsEncrypted},
+	vaults.GOOGLE:      {googleauth.Open, googleauth.IsEncrypted},
+	vaults.BITWARDEN:   {bitwarden.Open, bitwarden.IsEncrypted},
+	vaults.ENTE:        {ente.Open, ente.IsEncrypted},
+	vaults.FREEOTP:     {freeotp.Open, freeotp.IsEncrypted},
+	vaults.FREEOTPPLUS: {freeotpplus.Open, freeotpplus.IsEncrypted},
+}
+
 func main() {
 	log.SetFlags(0)
 	log.SetPrefix(fmt.Sprintf("%s: ", buildinfo.AppName))
@@ -31,47 +57,146 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	vault, err := open(cfg)
+	switch cfg.Command() {
+	case config.CONFIG:
+		err = printConfig(cfg)
+	case config.DOCTOR:
+		err = doctor(cfg)
+	case config.SCAN:
+		err = scan(cfg)
+	case config.ADD, config.EDIT, config.REMOVE:
+		err = modify(cfg)
+	default:
+		err = run(cfg)
+	}
+
 	if err != nil {
 		log.Fatalln(err)
 	}
+}
 
-	entries := vault.Entries()
-	if cfg.Query() != "" {
-		entry, err := vaults.Find(cfg.Query(), entries)
+// Opens all vaults and runs the current command on their entries.
+func run(cfg *config.Config) error {
+	entries, err := openAll(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.Command() {
+	case config.GET:
+		i, err := query(cfg, entries)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
-
-		token, exp := entry.GenerateTOTP()
-		until := max(exp-time.Now().Unix(), 0)
-
-		fmt.Printf("%s %s %ds\n", entry.Issuer, token, until)
-		os.Exit(0)
+		if cfg.QR() {
+			return showQR(entries[i])
+		}
+		return output.Write(os.Stdout, entries[i], cfg.Output(), cfg.Newline())
+	case config.LIST:
+		return output.List(os.Stdout, vaults.Filter(cfg.Query(), entries), cfg.Output())
+	case config.EXPORT:
+		return export(cfg, vaults.Filter(cfg.Query(), entries))
 	}
 
 	m := model.New(entries, cfg)
 	if _, err := tea.NewProgram(m).Run(); err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
-	if err := cfg.Persist(); err != nil {
-		log.Fatalln(err)
+	return cfg.Persist()
+}
+
+// Returns the index of the entry matching the query. Multiple matches are
+// resolved by the options "first" or "interactive", otherwise they are an
+// error.
+func query(cfg *config.Config, entries []vaults.Entry) (int, error) {
+	matches := vaults.Resolve(cfg.Query(), entries)
+	if len(matches) > 1 {
+		switch {
+		case cfg.First():
+			return matches[0], nil
+		case cfg.Interactive() && term.IsTerminal(int(os.Stdout.Fd())):
+			names := make([]string, 0, len(matches))
+			for _, i := range matches {
+				names = append(names, entries[i].QualifiedName())
+			}
+
+			i, err := input.Choose("Select an entry: ", names)
+			if err != nil {
+				return -1, err
+			}
+			return matches[i], nil
+		}
+	}
+
+	return vaults.Find(cfg.Query(), entries)
+}
+
+// Opens all configured vaults and merges their entries. If there is more
+// than one vault, each entry is marked with the name of its vault.
+func openAll(cfg *config.Config) ([]vaults.Entry, error) {
+	sources := cfg.Sources()
+	multiple := len(sources) > 1
+
+	entries := make([]vaults.Entry, 0)
+	for _, src := range sources {
+		prompt := "Password: "
+		if multiple {
+			prompt = fmt.Sprintf("Password for %s: ", src.Name())
+		}
+
+		vault, err := open(src, cfg, prompt)
+		if err != nil {
+			if multiple {
+				return nil, fmt.Errorf("%s: %w", src.Name(), err)
+			}
+			return nil, err
+		}
+
+		for _, e := range vault.Entries() {
+			if multiple {
+				e.Vault = src.Name()
+			}
+			entries = append(entries, e)
+		}
 	}
+
+	return entries, nil
 }
 
-func open(cfg *config.Config) (vaults.Vault, error) {
-	name := cfg.File
+func open(src config.Source, cfg *config.Config, prompt string) (vaults.Vault, error) {
+	name := src.File
 	if _, ok := os.LookupEnv("ANDCLI_HIDE_ABSPATH"); ok {
-		name = filepath.Base(cfg.File)
+		name = src.Name()
 	}
 	log.Printf("Opening %s ...", name)
 
-	pw, err := password(cfg.PasswdStdin())
+	backend, ok := backends[src.Type]
+	if !ok {
+		return nil, fmt.Errorf("vault type %q: not implemented", src.Type)
+	}
+
+	encrypted, err := backend.isEncrypted(src.File)
 	if err != nil {
 		return nil, err
 	}
 
+	// key files are validated to be used with keepass only.
+	openVault := backend.open
+	if src.KeyFile != "" {
+		openVault = func(filename string, pw []byte) (vaults.Vault, error) {
+			return keepass.OpenWithKeyFile(filename, pw, src.KeyFile)
+		}
+	}
+
+	// unencrypted exports do not need a password.
+	var pw []byte
+	if encrypted {
+		if pw, err = password(prompt, cfg.PasswdStdin()); err != nil {
+			return nil, err
+		}
+	}
+
 	defer func() {
 		for i := range pw {
 			pw[i] = 0
@@ -82,22 +207,7 @@ func open(cfg *config.Config) (vaults.Vault, error) {
 
 	var vault vaults.Vault
 	go func() {
-		switch cfg.Type {
-		case vaults.ANDOTP:
-			vault, err = andotp.Open(cfg.File, pw)
-		case vaults.AEGIS:
-			vault, err = aegis.Open(cfg.File, pw)
-		case vaults.TWOFAS:
-			vault, err = twofas.Open(cfg.File, pw)
-		case vaults.STRATUM:
-			vault, err = stratum.Open(cfg.File, pw)
-		case vaults.KEEPASS:
-			vault, err = keepass.Open(cfg.File, pw)
-		case vaults.PROTON:
-			vault, err = protonpass.Open(cfg.File, pw)
-		default:
-			vault, err = nil, fmt.Errorf("vault type %q: not implemented", cfg.Type)
-		}
+		vault, err = openVault(src.File, pw)
 		done <- struct{}{}
 	}()
 
@@ -110,9 +220,9 @@ func open(cfg *config.Config) (vaults.Vault, error) {
 	return vault, err
 }
 
-func password(piped bool) ([]byte, error) {
+func password(prompt string, piped bool) ([]byte, error) {
 	if !piped {
-		return input.Hidden("Password: ")
+		return input.Hidden(prompt)
 	}
 
 	log.Printf("Reading password from stdin ...")
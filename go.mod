@@ -6,9 +6,11 @@ require (
 	charm.land/bubbles/v2 v2.1.0
 	charm.land/bubbletea/v2 v2.0.7
 	charm.land/lipgloss/v2 v2.0.3
+	filippo.io/age v1.2.1
 	github.com/ProtonMail/gopenpgp/v3 v3.4.1
 	github.com/goccy/go-yaml v1.19.2
 	github.com/grijul/go-andotp v1.0.23
+	github.com/makiuchi-d/gozxing v0.1.1
 	github.com/sahilm/fuzzy v0.1.2
 	github.com/spf13/pflag v1.0.10
 	github.com/tobischo/gokeepasslib/v3 v3.6.2
@@ -37,4 +39,6 @@ require (
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	golang.org/x/sync v0.20.0 // indirect
 	golang.org/x/sys v0.45.0 // indirect
+	golang.org/x/text v0.37.0 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 )
@@ -26,6 +26,32 @@ func parse(path string) (*ast.File, error) {
 	return parser.ParseBytes(b, parser.ParseComments)
 }
 
+// Adds a new top level key to the file.
+func add(af *ast.File, key string, value any) error {
+	path, err := yaml.PathString("$")
+	if err != nil {
+		return err
+	}
+
+	node, err := yaml.ValueToNode(map[string]any{key: value})
+	if err != nil {
+		return err
+	}
+
+	return path.MergeFromNode(af, node)
+}
+
+// Returns true if the given path exists in the file.
+func exists(af *ast.File, pathStr string) bool {
+	path, err := yaml.PathString(pathStr)
+	if err != nil {
+		return false
+	}
+
+	_, err = path.FilterFile(af)
+	return err == nil
+}
+
 func replace(af *ast.File, pathStr string, value any) error {
 	path, err := yaml.PathString(pathStr)
 	if err != nil {
@@ -45,6 +71,10 @@ func replace(af *ast.File, pathStr string, value any) error {
 			Token:    node.GetToken(),
 			Value:    v,
 		}
+	case []Source:
+		if newNode, err = yaml.ValueToNode(v); err != nil {
+			return err
+		}
 	default:
 		newNode = &ast.StringNode{
 			BaseNode: &ast.BaseNode{},
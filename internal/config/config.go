@@ -11,6 +11,7 @@ import (
 
 	"github.com/goccy/go-yaml"
 	"github.com/tjblackheart/andcli/v2/internal/buildinfo"
+	"github.com/tjblackheart/andcli/v2/internal/output"
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 )
 
@@ -18,14 +19,29 @@ type (
 	Config struct {
 		File           string      `yaml:"file"`
 		Type           vaults.Type `yaml:"type"`
+		KeyFile        string      `yaml:"keyfile,omitempty"`
+		Vaults         []Source    `yaml:"vaults,omitempty"`
 		ClipboardCmd   string      `yaml:"clipboard_cmd"`
 		Options        *Opts       `yaml:"options"`
 		Theme          *Theme      `yaml:"theme"`
 		SessionTimeout int         `yaml:"session_timeout"`
 		//
 		path              string
+		command           Command
+		configAction      string
 		passwordFromStdin bool
 		query             string
+		first             bool
+		interactive       bool
+		output            output.Format
+		noNewline         bool
+		qr                bool
+		exportTo          vaults.Type
+		exportOut         string
+		uri               string
+		images            []string
+		changes           Changes
+		yes               bool
 		dirty             bool
 		timeout           int
 	}
@@ -33,6 +49,15 @@ type (
 	Opts struct {
 		ShowUsernames bool `yaml:"show_usernames"`
 		ShowTokens    bool `yaml:"show_tokens"`
+		GroupByTag    bool `yaml:"group_by_tag"`
+		CopyNextBelow int  `yaml:"copy_next_below"`
+	}
+
+	// Changes are the entry fields to set with the edit command. Nil
+	// fields are left unchanged, empty tags remove all tags.
+	Changes struct {
+		Issuer, Label *string
+		Tags          []string
 	}
 )
 
@@ -71,6 +96,11 @@ func create(dir string) (*Config, error) {
 		return nil, err
 	}
 
+	// config, doctor and scan work without (valid) vault files.
+	if cfg.command == CONFIG || cfg.command == DOCTOR || cfg.command == SCAN {
+		return cfg, nil
+	}
+
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
@@ -111,6 +141,25 @@ func (cfg Config) Persist() error {
 		"$.theme.white":            cfg.Theme.White,
 	}
 
+	// key file and vault list are optional and only added if in use.
+	switch {
+	case exists(af, "$.keyfile"):
+		patch["$.keyfile"] = cfg.KeyFile
+	case cfg.KeyFile != "":
+		if err := add(af, "keyfile", cfg.KeyFile); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case exists(af, "$.vaults"):
+		patch["$.vaults"] = cfg.Vaults
+	case len(cfg.Vaults) > 0:
+		if err := add(af, "vaults", cfg.Vaults); err != nil {
+			return err
+		}
+	}
+
 	// fallback: write full file if a key is missing (old version)
 	if err := apply(af, patch); err != nil {
 		b, err := yaml.Marshal(cfg)
@@ -123,6 +172,14 @@ func (cfg Config) Persist() error {
 	return os.WriteFile(cfg.path, []byte(af.String()), 0o600)
 }
 
+// Returns all vault sources, starting with the primary vault file. Their
+// names are unique.
+func (cfg Config) Sources() []Source {
+	sources := append([]Source{{File: cfg.File, Type: cfg.Type, KeyFile: cfg.KeyFile}}, cfg.Vaults...)
+	setNames(sources)
+	return sources
+}
+
 // Returns true if the flag option "passwd-stdin" was set.
 func (cfg Config) PasswdStdin() bool {
 	return cfg.passwordFromStdin
@@ -133,6 +190,76 @@ func (cfg Config) Query() string {
 	return strings.Trim(strings.ToValidUTF8(cfg.query, ""), " \r\n\t")
 }
 
+// Returns the command to run.
+func (cfg Config) Command() Command {
+	return cfg.command
+}
+
+// Returns the path of the config file.
+func (cfg Config) Path() string {
+	return cfg.path
+}
+
+// Returns true if the config command should print the file path only.
+func (cfg Config) PathOnly() bool {
+	return cfg.configAction == configPath
+}
+
+// Returns the format of the export command.
+func (cfg Config) ExportTo() vaults.Type {
+	return cfg.exportTo
+}
+
+// Returns the target file of the export command. Empty means stdout.
+func (cfg Config) ExportOut() string {
+	return cfg.exportOut
+}
+
+// Returns the URI of the add command.
+func (cfg Config) URI() string {
+	return cfg.uri
+}
+
+// Returns the image files of the scan command.
+func (cfg Config) Images() []string {
+	return cfg.images
+}
+
+// Returns the changes of the edit command.
+func (cfg Config) Changes() Changes {
+	return cfg.changes
+}
+
+// Returns true if the flag option "yes" was set.
+func (cfg Config) Yes() bool {
+	return cfg.yes
+}
+
+// Returns true if the flag option "first" was set.
+func (cfg Config) First() bool {
+	return cfg.first
+}
+
+// Returns true if the flag option "interactive" was set.
+func (cfg Config) Interactive() bool {
+	return cfg.interactive
+}
+
+// Returns true if the flag option "qr" was set.
+func (cfg Config) QR() bool {
+	return cfg.qr
+}
+
+// Returns the output format for queries.
+func (cfg Config) Output() output.Format {
+	return cfg.output
+}
+
+// Returns true if the query output should end with a newline.
+func (cfg Config) Newline() bool {
+	return !cfg.noNewline
+}
+
 // Returns the timeout value as time.Duration.
 func (cfg Config) DecryptionTimeoutD() time.Duration {
 	return time.Duration(cfg.timeout * int(time.Second))
@@ -162,6 +289,8 @@ func (cfg *Config) mergeExisting() error {
 
 	cfg.File = existing.File
 	cfg.Type = existing.Type
+	cfg.KeyFile = existing.KeyFile
+	cfg.Vaults = existing.Vaults
 	cfg.ClipboardCmd = existing.ClipboardCmd
 	cfg.SessionTimeout = existing.SessionTimeout
 
@@ -183,22 +312,16 @@ func (cfg *Config) validate() error {
 		return errors.New("no vault file specified")
 	}
 
-	if cfg.Type == "" {
-		return errors.New("no vault type specified")
-	}
-
-	var err error
-	if cfg.File, err = filepath.Abs(cfg.File); err != nil {
-		return fmt.Errorf("%s: %s", cfg.File, err)
-	}
-
-	fi, err := os.Stat(cfg.File)
-	if err != nil {
-		return fmt.Errorf("%s: %s", cfg.File, err)
+	primary := Source{File: cfg.File, Type: cfg.Type, KeyFile: cfg.KeyFile}
+	if err := primary.Validate(); err != nil {
+		return err
 	}
+	cfg.File, cfg.Type, cfg.KeyFile = primary.File, primary.Type, primary.KeyFile
 
-	if fi.IsDir() {
-		return fmt.Errorf("%s: is a directory, not a vault file", cfg.File)
+	for i := range cfg.Vaults {
+		if err := cfg.Vaults[i].Validate(); err != nil {
+			return err
+		}
 	}
 
 	// if set, check if the basic clipboard cmd is available in system PATH.
@@ -9,6 +9,7 @@ import (
 
 	"github.com/goccy/go-yaml"
 	"github.com/tjblackheart/andcli/v2/internal/buildinfo"
+	"github.com/tjblackheart/andcli/v2/internal/output"
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 )
 
@@ -70,6 +71,7 @@ func TestConfig_mergeExisting(t *testing.T) {
 				Options: &Opts{
 					ShowUsernames: true,
 					ShowTokens:    true,
+					CopyNextBelow: 5,
 				},
 				path: path,
 			},
@@ -126,7 +128,7 @@ func TestConfig_validate(t *testing.T) {
 		},
 		{
 			"validates missing file type",
-			&Config{File: "test.json", Type: "", ClipboardCmd: ""},
+			&Config{File: path, Type: "", ClipboardCmd: ""},
 			true,
 			"no vault type",
 		},
@@ -288,11 +290,142 @@ theme:
 	}
 }
 
+func TestConfig_Persist_vaults(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yaml")
+
+	original := `# comment
+file: /path/to/vault.json
+type: aegis
+session_timeout: 300
+options:
+  show_usernames: true
+  show_tokens: false
+clipboard_cmd: ""
+theme:
+  base: "#39A02E"
+  green: "#39A02E"
+  yellow: "#DB9F1F"
+  red: "#f10000"
+  grey: "#424242"
+  black: "#000000"
+  white: "#FFFFFF"
+`
+
+	if err := os.WriteFile(fname, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		File:    "/path/to/vault.json",
+		Type:    vaults.AEGIS,
+		Vaults:  []Source{{File: "/path/to/other.2fas", Type: vaults.TWOFAS}},
+		Options: &Opts{ShowUsernames: true},
+		Theme:   &DefaultTheme,
+		path:    fname,
+		dirty:   true,
+	}
+
+	if err := cfg.Persist(); err != nil {
+		t.Fatalf("Config.Persist() error = %v", err)
+	}
+
+	existing := &Config{path: fname}
+	if err := existing.mergeExisting(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(existing.Vaults, cfg.Vaults) {
+		t.Errorf("Config.Persist() vaults = %v, want %v", existing.Vaults, cfg.Vaults)
+	}
+
+	// removing all vaults must clear the list.
+	cfg.Vaults = nil
+	if err := cfg.Persist(); err != nil {
+		t.Fatalf("Config.Persist() error = %v", err)
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), "# comment") {
+		t.Error("comment was not preserved")
+	}
+
+	if strings.Contains(string(b), "other.2fas") {
+		t.Errorf("Config.Persist() vaults not cleared:\n%s", b)
+	}
+}
+
+func TestConfig_Persist_keyfile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "config.yaml")
+
+	original := `# comment
+file: /path/to/vault.kdbx
+type: keepass
+session_timeout: 300
+options:
+  show_usernames: true
+  show_tokens: false
+clipboard_cmd: ""
+theme:
+  base: "#39A02E"
+  green: "#39A02E"
+  yellow: "#DB9F1F"
+  red: "#f10000"
+  grey: "#424242"
+  black: "#000000"
+  white: "#FFFFFF"
+`
+
+	if err := os.WriteFile(fname, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{
+		File:    "/path/to/vault.kdbx",
+		Type:    vaults.KEEPASS,
+		KeyFile: "/path/to/vault.keyx",
+		Options: &Opts{ShowUsernames: true},
+		Theme:   &DefaultTheme,
+		path:    fname,
+		dirty:   true,
+	}
+
+	for _, keyfile := range []string{"/path/to/vault.keyx", "/path/to/other.keyx"} {
+		cfg.KeyFile = keyfile
+		if err := cfg.Persist(); err != nil {
+			t.Fatalf("Config.Persist() error = %v", err)
+		}
+
+		existing := &Config{path: fname}
+		if err := existing.mergeExisting(); err != nil {
+			t.Fatal(err)
+		}
+
+		if existing.KeyFile != keyfile {
+			t.Errorf("Config.Persist() keyfile = %q, want %q", existing.KeyFile, keyfile)
+		}
+	}
+
+	b, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), "# comment") {
+		t.Error("comment was not preserved")
+	}
+}
+
 func Test_create(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+
 	cfgDir := os.TempDir()
-	*vfile = filepath.Join("testdata", "empty.json")
-	*vtype = "aegis"
-	abs, _ := filepath.Abs(*vfile)
+	os.Args = []string{"andcli", "-t", "aegis", filepath.Join("testdata", "empty.json")}
+	abs, _ := filepath.Abs(filepath.Join("testdata", "empty.json"))
 
 	cfg, err := create(cfgDir)
 	if err != nil {
@@ -302,7 +435,8 @@ func Test_create(t *testing.T) {
 	// default config
 	want := &Config{
 		File:           abs,
-		Type:           vaults.Type(*vtype),
+		Type:           vaults.AEGIS,
+		Vaults:         []Source{},
 		SessionTimeout: 300,
 		ClipboardCmd:   "",
 		Options: &Opts{
@@ -311,6 +445,8 @@ func Test_create(t *testing.T) {
 		},
 		Theme:   &DefaultTheme,
 		path:    filepath.Join(cfgDir, buildinfo.AppName, "config.yaml"),
+		command: TUI,
+		output:  output.TEXT,
 		dirty:   true,
 		timeout: 5,
 	}
@@ -426,6 +562,24 @@ func TestConfig_Flags(t *testing.T) {
 				}
 			},
 		},
+		{
+			"sets multiple files",
+			[]string{"andcli", tmpFile.Name(), tmpFile.Name()},
+			func(c *Config) {
+				if c.File != absPath || len(c.Vaults) != 1 || c.Vaults[0].File != absPath {
+					t.Errorf("File = %q, Vaults = %v, want %q twice", c.File, c.Vaults, absPath)
+				}
+			},
+		},
+		{
+			"sets types in order",
+			[]string{"andcli", "-t", "aegis,twofas", tmpFile.Name(), tmpFile.Name()},
+			func(c *Config) {
+				if c.Type != "aegis" || c.Vaults[0].Type != "twofas" {
+					t.Errorf("Types = %q, %q, want %q, %q", c.Type, c.Vaults[0].Type, "aegis", "twofas")
+				}
+			},
+		},
 		{
 			"sets session timeout",
 			[]string{"andcli", "--session-timeout", "600", "-t", "aegis", tmpFile.Name()},
@@ -444,6 +598,34 @@ func TestConfig_Flags(t *testing.T) {
 				}
 			},
 		},
+		{
+			"sets query options",
+			[]string{"andcli", "-q", "git", "--first", "-i", "-l", "-t", "aegis", tmpFile.Name()},
+			func(c *Config) {
+				if !c.First() || !c.Interactive() || c.Command() != LIST {
+					t.Errorf("First() = %v, Interactive() = %v, Command() = %v, want true, true, list", c.First(), c.Interactive(), c.Command())
+				}
+			},
+		},
+		{
+			"sets output format",
+			[]string{"andcli", "-q", "git", "-o", "token", "-n", "-t", "aegis", tmpFile.Name()},
+			func(c *Config) {
+				if c.Output() != output.TOKEN || c.Newline() {
+					t.Errorf("Output() = %q, Newline() = %v, want %q, false", c.Output(), c.Newline(), output.TOKEN)
+				}
+			},
+		},
+		{
+			"sets key files in order",
+			[]string{"andcli", "-k", ",vault.keyx", tmpFile.Name(), tmpFile.Name()},
+			func(c *Config) {
+				abs, _ := filepath.Abs("vault.keyx")
+				if c.KeyFile != "" || c.Vaults[0].KeyFile != abs {
+					t.Errorf("KeyFiles = %q, %q, want %q, %q", c.KeyFile, c.Vaults[0].KeyFile, "", abs)
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -460,3 +642,138 @@ func TestConfig_Flags(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_Flags_commands(t *testing.T) {
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	tmpFile, err := os.CreateTemp("", "dummy.vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	absPath, _ := filepath.Abs(tmpFile.Name())
+
+	tests := []struct {
+		name  string
+		args  []string
+		want  Command
+		check func(*Config) bool
+		fails bool
+	}{
+		{"defaults to tui", []string{"andcli", tmpFile.Name()}, TUI, nil, false},
+		{"legacy query", []string{"andcli", "-q", "github", tmpFile.Name()}, GET, nil, false},
+		{"legacy list", []string{"andcli", "-l", tmpFile.Name()}, LIST, nil, false},
+		{
+			"tui",
+			[]string{"andcli", "tui", "--session-timeout", "60", tmpFile.Name()},
+			TUI,
+			func(c *Config) bool { return c.SessionTimeout == 60 && c.File == absPath },
+			false,
+		},
+		{
+			"get reads the query and files",
+			[]string{"andcli", "get", "-o", "json", "github", tmpFile.Name()},
+			GET,
+			func(c *Config) bool { return c.Query() == "github" && c.File == absPath && c.Output() == output.JSON },
+			false,
+		},
+		{"fails: get without query", []string{"andcli", "get"}, GET, nil, true},
+		{"get qr", []string{"andcli", "get", "--qr", "github"}, GET, func(c *Config) bool { return c.QR() }, false},
+		{
+			"list with query flag",
+			[]string{"andcli", "list", "-q", "tag:work"},
+			LIST,
+			func(c *Config) bool { return c.Query() == "tag:work" },
+			false,
+		},
+		{
+			"export",
+			[]string{"andcli", "export", "--to", "otpauth", "-o", "out.txt"},
+			EXPORT,
+			func(c *Config) bool { return c.ExportTo() == vaults.OTPAUTH && c.ExportOut() == "out.txt" },
+			false,
+		},
+		{
+			"add reads the uri",
+			[]string{"andcli", "add", "otpauth://totp/x?secret=abc", tmpFile.Name()},
+			ADD,
+			func(c *Config) bool { return c.URI() == "otpauth://totp/x?secret=abc" && c.File == absPath },
+			false,
+		},
+		{"fails: add without uri", []string{"andcli", "add"}, ADD, nil, true},
+		{
+			"add reads an image",
+			[]string{"andcli", "add", "code.png", tmpFile.Name()},
+			ADD,
+			func(c *Config) bool { return c.URI() == "code.png" && c.File == absPath },
+			false,
+		},
+		{"fails: add to multiple files", []string{"andcli", "add", "otpauth://totp/x", "a", "b"}, ADD, nil, true},
+		{
+			"edit",
+			[]string{"andcli", "edit", "--label", "bob", "--tags", "work, ,dev", "github"},
+			EDIT,
+			func(c *Config) bool {
+				ch := c.Changes()
+				return c.Query() == "github" && ch.Issuer == nil && *ch.Label == "bob" && reflect.DeepEqual(ch.Tags, []string{"work", "dev"})
+			},
+			false,
+		},
+		{
+			"edit: empty tags",
+			[]string{"andcli", "edit", "--tags", "", "github"},
+			EDIT,
+			func(c *Config) bool { return c.Changes().Tags != nil && len(c.Changes().Tags) == 0 },
+			false,
+		},
+		{"fails: edit without changes", []string{"andcli", "edit", "github"}, EDIT, nil, true},
+		{"remove", []string{"andcli", "remove", "-y", "github"}, REMOVE, func(c *Config) bool { return c.Yes() && c.Query() == "github" }, false},
+		{"fails: remove without query", []string{"andcli", "remove"}, REMOVE, nil, true},
+		{
+			"export to keepass",
+			[]string{"andcli", "export", "--to", "keepass", "-o", "out.kdbx"},
+			EXPORT,
+			func(c *Config) bool { return c.ExportTo() == vaults.KEEPASS },
+			false,
+		},
+		{"fails: export format", []string{"andcli", "export", "--to", "stratum"}, EXPORT, nil, true},
+		{"fails: export without format", []string{"andcli", "export"}, EXPORT, nil, true},
+		{"config", []string{"andcli", "config"}, CONFIG, func(c *Config) bool { return !c.PathOnly() }, false},
+		{"config path", []string{"andcli", "config", "path"}, CONFIG, func(c *Config) bool { return c.PathOnly() }, false},
+		{"fails: config action", []string{"andcli", "config", "edit"}, CONFIG, nil, true},
+		{"doctor", []string{"andcli", "doctor", tmpFile.Name()}, DOCTOR, nil, false},
+		{
+			"scan reads the images",
+			[]string{"andcli", "scan", "-o", "json", "a.png", "b.jpg"},
+			SCAN,
+			func(c *Config) bool {
+				return reflect.DeepEqual(c.Images(), []string{"a.png", "b.jpg"}) && c.Output() == output.JSON
+			},
+			false,
+		},
+		{"fails: scan without image", []string{"andcli", "scan"}, SCAN, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = tt.args
+			cfg := &Config{Options: &Opts{}, Theme: &DefaultTheme}
+
+			err := cfg.parseFlags()
+			if (err != nil) != tt.fails {
+				t.Fatalf("parseFlags() error = %v, wantErr %v", err, tt.fails)
+			}
+			if tt.fails {
+				return
+			}
+
+			if cfg.Command() != tt.want {
+				t.Errorf("Command() = %q, want %q", cfg.Command(), tt.want)
+			}
+			if tt.check != nil && !tt.check(cfg) {
+				t.Errorf("parseFlags() unexpected config: %#v", cfg)
+			}
+		})
+	}
+}
@@ -5,47 +5,226 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	flag "github.com/spf13/pflag"
 	"github.com/tjblackheart/andcli/v2/internal/buildinfo"
+	"github.com/tjblackheart/andcli/v2/internal/output"
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 )
 
-var (
-	set               = flag.NewFlagSet("default", flag.ExitOnError)
-	vfile             = set.StringP("file", "f", "", "Path to the encrypted vault (deprecated: Pass the filename directly)")
-	vtype             = set.StringP("type", "t", "", fmt.Sprintf("Vault type (%s)", vaults.StrTypes()))
-	cmd               = set.StringP("clipboard-cmd", "c", "", "A custom clipboard command, including args (xclip, wl-copy, pbcopy etc.)")
-	pwstdin           = set.Bool("passwd-stdin", false, "Read the vault password from stdin. If set, skips the password input.")
-	query             = set.StringP("query", "q", "", "Query the vault directly and skip TUI functionality")
-	version           = set.BoolP("version", "v", false, "Prints version info and exits")
-	decryptionTimeout = set.Int("timeout", 5, "Timeout for decrypting the vault file, in seconds")
-	sessionTimeout    = set.Int("session-timeout", 300, "Auto-close after N seconds of inactivity (0=disabled)")
-	help              = set.BoolP("help", "h", false, "Show this help")
+// Command is a subcommand of the application. Each command has its own
+// set of flags.
+type Command string
+
+// Supported commands. Without a command, the flags of tui, get and list
+// are accepted and the command is derived from them.
+const (
+	TUI    Command = "tui"
+	GET    Command = "get"
+	LIST   Command = "list"
+	ADD    Command = "add"
+	EDIT   Command = "edit"
+	REMOVE Command = "remove"
+	EXPORT Command = "export"
+	SCAN   Command = "scan"
+	CONFIG Command = "config"
+	DOCTOR Command = "doctor"
+)
+
+// Arguments of the config command.
+const (
+	configShow = "show"
+	configPath = "path"
 )
 
-// Parses given flags into the existing config.
+// Formats of the export command.
+var exportTypes = []vaults.Type{vaults.AEGIS, vaults.TWOFAS, vaults.OTPAUTH, vaults.KEEPASS}
+
+// command arguments and description for the help output.
+type commandInfo struct {
+	cmd        Command
+	args, desc string
+}
+
+// commands in help order.
+var commands = []commandInfo{
+	{TUI, "[<path/to/file> ...]", "Show all entries in the terminal UI (default)"},
+	{GET, "<query> [<path/to/file> ...]", "Print the token of the entry matching the query"},
+	{LIST, "[<path/to/file> ...]", "List all entries without secrets"},
+	{ADD, "<uri|image> [<path/to/file>]", "Add the entries of an otpauth:// or otpauth-migration:// URI or QR code image to the vault"},
+	{EDIT, "<query> [<path/to/file>]", "Change the issuer, label or tags of an entry"},
+	{REMOVE, "<query> [<path/to/file>]", "Remove an entry from the vault"},
+	{EXPORT, "[<path/to/file> ...]", "Export all entries to another format"},
+	{SCAN, "<image> [<image> ...]", "Print the tokens of the entries in QR code images (PNG, JPEG)"},
+	{CONFIG, "[show|path]", "Print the config file or its path"},
+	{DOCTOR, "[<path/to/file> ...]", "Check the config, vault files and clipboard"},
+}
+
+// flags holds the flag values of a single parse run. Not every command
+// defines every flag.
+type flags struct {
+	file, types, keyFiles, clipboardCmd string
+	query, output, exportTo, exportOut  string
+	issuer, label, tags                 string
+	passwdStdin, list, first, yes, qr   bool
+	interactive, noNewline              bool
+	version, help                       bool
+	decryptionTimeout, sessionTimeout   int
+}
+
+// Returns the flag set of the command. Without a command, the set contains
+// all flags of tui, get and list, like before there were commands.
+func newFlagSet(cmd Command, f *flags) *flag.FlagSet {
+	set := flag.NewFlagSet(string(cmd), flag.ExitOnError)
+	legacy := cmd == ""
+
+	if cmd != CONFIG && cmd != SCAN {
+		set.StringVarP(&f.types, "type", "t", "", fmt.Sprintf("Vault type (%s). Detected from the file if omitted. Comma separated for multiple files", vaults.StrTypes()))
+		set.StringVarP(&f.keyFiles, "keyfile", "k", "", "Path to a KeePass key file. Comma separated for multiple files")
+		set.BoolVar(&f.passwdStdin, "passwd-stdin", false, "Read the vault password from stdin. If set, skips the password input.")
+		set.IntVar(&f.decryptionTimeout, "timeout", 5, "Timeout for decrypting the vault file, in seconds")
+	}
+
+	if legacy {
+		set.StringVarP(&f.file, "file", "f", "", "Path to the encrypted vault (deprecated: Pass the filename directly)")
+		set.StringVarP(&f.query, "query", "q", "", "Query the vault directly and skip TUI functionality")
+		set.BoolVarP(&f.list, "list", "l", false, "List all entries (or the matches of --query) without secrets and exit")
+		set.BoolVarP(&f.version, "version", "v", false, "Prints version info and exits")
+	}
+
+	if legacy || cmd == TUI {
+		set.StringVarP(&f.clipboardCmd, "clipboard-cmd", "c", "", "A custom clipboard command, including args (xclip, wl-copy, pbcopy etc.)")
+		set.IntVar(&f.sessionTimeout, "session-timeout", 300, "Auto-close after N seconds of inactivity (0=disabled)")
+	}
+
+	if legacy || cmd == GET {
+		set.BoolVar(&f.first, "first", false, "Use the best match if a query matches multiple entries")
+		set.BoolVarP(&f.interactive, "interactive", "i", false, "Choose from multiple query matches, if stdout is a terminal")
+		set.BoolVarP(&f.noNewline, "no-newline", "n", false, "Omit the trailing newline of the query output")
+		set.BoolVar(&f.qr, "qr", false, "Show the QR code of the entry instead of the token, after a confirmation")
+	}
+
+	switch cmd {
+	case "":
+		set.StringVarP(&f.output, "output", "o", "text", fmt.Sprintf("Output format of a query (%s). Lists support text and json", output.StrFormats()))
+	case GET, SCAN:
+		set.StringVarP(&f.output, "output", "o", "text", fmt.Sprintf("Output format (%s)", output.StrFormats()))
+	case LIST:
+		set.StringVarP(&f.query, "query", "q", "", "List the matches of the query only")
+		set.StringVarP(&f.output, "output", "o", "text", "Output format (text, json)")
+	case EDIT:
+		set.StringVar(&f.issuer, "issuer", "", "New issuer of the entry")
+		set.StringVar(&f.label, "label", "", "New label (account name) of the entry")
+		set.StringVar(&f.tags, "tags", "", "New tags of the entry, comma separated. Empty removes all tags")
+	case REMOVE:
+		set.BoolVarP(&f.yes, "yes", "y", false, "Remove the entry without confirmation")
+	case EXPORT:
+		set.StringVarP(&f.query, "query", "q", "", "Export the matches of the query only")
+		set.StringVar(&f.exportTo, "to", "", fmt.Sprintf("Export format (%s), required. All but otpauth are encrypted with a new password", strExportTypes()))
+		set.StringVarP(&f.exportOut, "out", "o", "", "Path to the exported file. Prints to stdout if omitted")
+	}
+
+	set.BoolVarP(&f.help, "help", "h", false, "Show this help")
+
+	return set
+}
+
+// Parses the command and its flags into the existing config.
 func (cfg *Config) parseFlags() error {
-	set.Usage = func() { usage(true) }
+	args := os.Args[1:]
+	if len(args) > 0 && isCommand(args[0]) {
+		cfg.command, args = Command(args[0]), args[1:]
+	}
 
-	if err := set.Parse(os.Args[1:]); err != nil {
+	f := new(flags)
+	set := newFlagSet(cfg.command, f)
+	set.Usage = func() { usage(set, cfg.command, true) }
+
+	if err := set.Parse(args); err != nil {
 		log.Printf("%s: %s", buildinfo.AppName, err)
-		usage(false)
+		usage(set, cfg.command, false)
 		os.Exit(1)
 	}
 
-	if *version {
+	if f.version {
 		fmt.Println(buildinfo.Long())
 		os.Exit(0)
 	}
 
-	if *help {
-		usage(true)
+	if f.help {
+		usage(set, cfg.command, true)
 		os.Exit(0)
 	}
 
-	if *vfile != "" {
-		abs, err := filepath.Abs(*vfile)
+	files := set.Args()
+
+	switch cfg.command {
+	case "":
+		cfg.command = TUI
+		if f.query != "" {
+			cfg.command = GET
+		}
+		if f.list {
+			cfg.command = LIST
+		}
+	case GET, EDIT, REMOVE:
+		if len(files) == 0 {
+			return fmt.Errorf("%s: missing query", cfg.command)
+		}
+		f.query, files = files[0], files[1:]
+	case ADD:
+		if len(files) == 0 {
+			return fmt.Errorf("%s: missing URI", ADD)
+		}
+		cfg.uri, files = files[0], files[1:]
+	case SCAN:
+		if len(files) == 0 {
+			return fmt.Errorf("%s: missing image", SCAN)
+		}
+		cfg.images, files = files, nil
+	case CONFIG:
+		cfg.configAction = configShow
+		if len(files) > 0 {
+			cfg.configAction = files[0]
+		}
+		if len(files) > 1 || (cfg.configAction != configShow && cfg.configAction != configPath) {
+			return fmt.Errorf("%s: invalid arguments %q, want %q or %q", CONFIG, files, configShow, configPath)
+		}
+		files = nil
+	}
+
+	// only the first vault is modified.
+	if cfg.command == ADD || cfg.command == EDIT || cfg.command == REMOVE {
+		if len(files) > 1 {
+			return fmt.Errorf("%s: too many vault files, want one", cfg.command)
+		}
+	}
+
+	if cfg.command == EDIT {
+		if set.Changed("issuer") {
+			cfg.changes.Issuer = &f.issuer
+		}
+		if set.Changed("label") {
+			cfg.changes.Label = &f.label
+		}
+		if set.Changed("tags") {
+			cfg.changes.Tags = make([]string, 0)
+			for _, t := range strings.Split(f.tags, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					cfg.changes.Tags = append(cfg.changes.Tags, t)
+				}
+			}
+		}
+
+		if cfg.changes.Issuer == nil && cfg.changes.Label == nil && cfg.changes.Tags == nil {
+			return fmt.Errorf("%s: nothing to change, use --issuer, --label or --tags", EDIT)
+		}
+	}
+
+	if f.file != "" {
+		abs, err := filepath.Abs(f.file)
 		if err != nil {
 			return err
 		}
@@ -53,58 +232,152 @@ func (cfg *Config) parseFlags() error {
 		cfg.dirty = true
 	}
 
-	if *vtype != "" {
-		cfg.Type = vaults.Type(*vtype)
+	if f.clipboardCmd != "" {
+		cfg.ClipboardCmd = f.clipboardCmd
 		cfg.dirty = true
 	}
 
-	if *cmd != "" {
-		cfg.ClipboardCmd = *cmd
-		cfg.dirty = true
+	cfg.passwordFromStdin = f.passwdStdin
+	cfg.query = f.query
+	cfg.first = f.first
+	cfg.interactive = f.interactive
+	cfg.noNewline = f.noNewline
+	cfg.exportTo = vaults.Type(f.exportTo)
+	cfg.exportOut = f.exportOut
+	cfg.yes = f.yes
+	cfg.qr = f.qr
+
+	cfg.output = output.TEXT
+	if f.output != "" {
+		cfg.output = output.Format(f.output)
+	}
+
+	if !slices.Contains(output.Formats(), cfg.output) {
+		return fmt.Errorf("output format %q: not supported", cfg.output)
 	}
 
-	if *pwstdin {
-		cfg.passwordFromStdin = true
+	// the otpauth list is plain text, so there is no default format.
+	if cfg.command == EXPORT && cfg.exportTo == "" {
+		return fmt.Errorf("export: missing format, set --to (%s)", strExportTypes())
 	}
 
-	if *query != "" {
-		cfg.query = *query
+	if cfg.command == EXPORT && !slices.Contains(exportTypes, cfg.exportTo) {
+		return fmt.Errorf("export format %q: not supported", cfg.exportTo)
 	}
 
-	if set.Arg(0) != "" {
-		abs, err := filepath.Abs(set.Arg(0))
+	// positional args replace all configured vaults. Types will be
+	// detected, unless given via flag.
+	if len(files) > 0 {
+		sources := make([]Source, 0, len(files))
+		for _, arg := range files {
+			abs, err := filepath.Abs(arg)
+			if err != nil {
+				return err
+			}
+			sources = append(sources, Source{File: abs})
+		}
+
+		cfg.File, cfg.Type, cfg.KeyFile = sources[0].File, "", ""
+		cfg.Vaults = sources[1:]
+		cfg.dirty = true
+	}
+
+	// types are applied in order of the vault files.
+	for i, t := range strings.Split(f.types, ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+
+		switch {
+		case i == 0:
+			cfg.Type = vaults.Type(t)
+		case i <= len(cfg.Vaults):
+			cfg.Vaults[i-1].Type = vaults.Type(t)
+		}
+		cfg.dirty = true
+	}
+
+	// key files are applied in order of the vault files, like types.
+	for i, k := range strings.Split(f.keyFiles, ",") {
+		if k = strings.TrimSpace(k); k == "" {
+			continue
+		}
+
+		abs, err := filepath.Abs(k)
 		if err != nil {
 			return err
 		}
-		cfg.File = abs
+
+		switch {
+		case i == 0:
+			cfg.KeyFile = abs
+		case i <= len(cfg.Vaults):
+			cfg.Vaults[i-1].KeyFile = abs
+		}
 		cfg.dirty = true
 	}
 
-	cfg.timeout = *decryptionTimeout
+	cfg.timeout = f.decryptionTimeout
 	if cfg.timeout <= 0 {
 		cfg.timeout = 5
 	}
 
 	if set.Changed("session-timeout") {
-		cfg.SessionTimeout = max(*sessionTimeout, 0)
+		cfg.SessionTimeout = max(f.sessionTimeout, 0)
 		cfg.dirty = true
 	}
 
 	return nil
 }
 
-// prints custom formatted usage information
-func usage(includeDescription bool) {
+// Returns the export formats as a comma separated string.
+func strExportTypes() string {
+	s := make([]string, 0, len(exportTypes))
+	for _, t := range exportTypes {
+		s = append(s, t.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+// Returns true if s is the name of a command.
+func isCommand(s string) bool {
+	return slices.ContainsFunc(commands, func(c commandInfo) bool {
+		return string(c.cmd) == s
+	})
+}
+
+// prints custom formatted usage information. Without a command, all
+// commands are listed.
+func usage(set *flag.FlagSet, cmd Command, includeDescription bool) {
+	for _, c := range commands {
+		if c.cmd != cmd {
+			continue
+		}
+
+		if includeDescription {
+			fmt.Printf("%s %s - %s\n", buildinfo.AppName, c.cmd, c.desc)
+		}
+
+		fmt.Fprintf(set.Output(), "\nUsage: %s %s [options] %s\n\nOptions:\n", os.Args[0], c.cmd, c.args)
+		set.PrintDefaults()
+		return
+	}
+
 	if includeDescription {
 		fmt.Printf("%s - %s\n", buildinfo.AppName, buildinfo.Description)
 	}
 
 	msg := `
-Usage: %s [options] <path/to/file>
+Usage: %s [command] [options] <path/to/file> [<path/to/file> ...]
 
-Options:
+Commands:
 `
 
 	fmt.Fprintf(set.Output(), msg, os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(set.Output(), "  %-8s %s\n", c.cmd, c.desc)
+	}
+
+	fmt.Fprintf(set.Output(), "\nRun '%s <command> --help' for the options of a command.\n\nOptions:\n", os.Args[0])
 	set.PrintDefaults()
 }
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+// Source is a single vault file and its type.
+type Source struct {
+	File    string      `yaml:"file"`
+	Type    vaults.Type `yaml:"type"`
+	KeyFile string      `yaml:"keyfile,omitempty"` // keepass only
+	name    string      // set by setNames
+}
+
+// Returns the name of the vault file: its base name, plus as many parent
+// directories as needed to tell it apart from the other sources.
+func (s Source) Name() string {
+	if s.name != "" {
+		return s.name
+	}
+	return filepath.Base(s.File)
+}
+
+// Sets the names of the sources. Files with the same base name, i.e.
+// a/backup.json and b/backup.json, get parent directories added until
+// their names differ.
+func setNames(sources []Source) {
+	depth := make([]int, len(sources))
+	for i := range depth {
+		depth[i] = 1
+	}
+
+	for {
+		count := make(map[string]int)
+		for i := range sources {
+			sources[i].name = pathSuffix(sources[i].File, depth[i])
+			count[sources[i].name]++
+		}
+
+		grown := false
+		for i := range sources {
+			if count[sources[i].name] > 1 && depth[i] < len(pathElems(sources[i].File)) {
+				depth[i]++
+				grown = true
+			}
+		}
+
+		if !grown {
+			return
+		}
+	}
+}
+
+// Returns the last n elements of the path.
+func pathSuffix(path string, n int) string {
+	elems := pathElems(path)
+	return filepath.Join(elems[max(len(elems)-n, 0):]...)
+}
+
+// Returns the non-empty elements of the path.
+func pathElems(path string) []string {
+	elems := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	return slices.DeleteFunc(elems, func(s string) bool { return s == "" })
+}
+
+// Validate checks the vault and key file and detects the type, if necessary.
+func (s *Source) Validate() error {
+	var err error
+	if s.File, err = filepath.Abs(s.File); err != nil {
+		return fmt.Errorf("%s: %s", s.File, err)
+	}
+
+	fi, err := os.Stat(s.File)
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.File, err)
+	}
+
+	if fi.IsDir() {
+		return fmt.Errorf("%s: is a directory, not a vault file", s.File)
+	}
+
+	if err := s.detectType(); err != nil {
+		return err
+	}
+
+	return s.validateKeyFile()
+}
+
+// Validates the key file, if set. Only keepass supports key files.
+func (s *Source) validateKeyFile() error {
+	if s.KeyFile == "" {
+		return nil
+	}
+
+	if s.Type != vaults.KEEPASS {
+		return fmt.Errorf("%s: key files are not supported for vault type %q", s.Name(), s.Type)
+	}
+
+	var err error
+	if s.KeyFile, err = filepath.Abs(s.KeyFile); err != nil {
+		return fmt.Errorf("%s: %s", s.KeyFile, err)
+	}
+
+	if _, err := os.Stat(s.KeyFile); err != nil {
+		return fmt.Errorf("%s: %s", s.KeyFile, err)
+	}
+
+	return nil
+}
+
+// Detects the vault type from the file content if no type is given.
+// A configured type always wins, but a mismatch will be reported.
+func (s *Source) detectType() error {
+	detected, err := vaults.Detect(s.File)
+	if s.Type != "" {
+		if err == nil && detected != s.Type {
+			log.Printf("warning: %s: vault type is set to %q, but the file looks like %q", s.Name(), s.Type, detected)
+		}
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: no vault type specified: %s", s.Name(), err)
+	}
+
+	s.Type = detected
+	return nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestSource_Validate(t *testing.T) {
+	aegis := filepath.Join("..", "vaults", "aegis", "testdata", "aegis-export-test.json")
+	abs, _ := filepath.Abs(aegis)
+	kdbx := filepath.Join("..", "vaults", "keepass", "testdata", "keepass-keyfile.kdbx")
+	kdbxAbs, _ := filepath.Abs(kdbx)
+	keyfile := filepath.Join("..", "vaults", "keepass", "testdata", "keepass-test.keyx")
+	keyfileAbs, _ := filepath.Abs(keyfile)
+
+	tests := []struct {
+		name     string
+		have     *Source
+		want     *Source
+		contains string
+	}{
+		{"detects type", &Source{File: aegis}, &Source{File: abs, Type: vaults.AEGIS}, ""},
+		{"keeps configured type", &Source{File: aegis, Type: vaults.TWOFAS}, &Source{File: abs, Type: vaults.TWOFAS}, ""},
+		{"fails: unknown type", &Source{File: "testdata/empty.json"}, nil, "no vault type"},
+		{"fails: missing file", &Source{File: "testdata/nosuchfile.json"}, nil, "no such file"},
+		{"fails: directory", &Source{File: "testdata"}, nil, "is a directory"},
+		{"keeps key file", &Source{File: kdbx, KeyFile: keyfile}, &Source{File: kdbxAbs, Type: vaults.KEEPASS, KeyFile: keyfileAbs}, ""},
+		{"fails: missing key file", &Source{File: kdbx, KeyFile: "testdata/nosuchfile.keyx"}, nil, "no such file"},
+		{"fails: key file for other type", &Source{File: aegis, KeyFile: keyfile}, nil, "key files are not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.have.Validate()
+			if tt.want == nil {
+				if err == nil || !strings.Contains(err.Error(), tt.contains) {
+					t.Fatalf("Source.Validate() error = %v, want %q", err, tt.contains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Source.Validate() error = %v", err)
+			}
+
+			if *tt.have != *tt.want {
+				t.Errorf("Source.Validate() = %v, want %v", tt.have, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_detectType(t *testing.T) {
+	aegis := filepath.Join("..", "vaults", "aegis", "testdata", "aegis-export-test.json")
+
+	tests := []struct {
+		name     string
+		have     *Source
+		want     vaults.Type
+		fails    bool
+		contains string
+	}{
+		{"detects type", &Source{File: aegis}, vaults.AEGIS, false, ""},
+		{"keeps configured type", &Source{File: aegis, Type: vaults.TWOFAS}, vaults.TWOFAS, false, `looks like "aegis"`},
+		{"fails: unknown type", &Source{File: "testdata/empty.json"}, "", true, ""},
+	}
+
+	buf := new(bytes.Buffer)
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer buf.Reset()
+
+			err := tt.have.detectType()
+			if (err != nil) != tt.fails {
+				t.Fatalf("Source.detectType() error = %v, wantErr %v", err, tt.fails)
+			}
+
+			if tt.have.Type != tt.want {
+				t.Errorf("Source.detectType() type = %q, want %q", tt.have.Type, tt.want)
+			}
+
+			if !strings.Contains(buf.String(), tt.contains) {
+				t.Errorf("Source.detectType() log = %q, want %q", buf.String(), tt.contains)
+			}
+		})
+	}
+}
+
+func Test_setNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{"base names", []string{"/a/vault.json", "/b/backup.2fas"}, []string{"vault.json", "backup.2fas"}},
+		{
+			"same base name",
+			[]string{"/home/a/backup.json", "/home/b/backup.json", "/home/c/vault.json"},
+			[]string{"a/backup.json", "b/backup.json", "vault.json"},
+		},
+		{
+			"same parent name",
+			[]string{"/x/sync/backup.json", "/y/sync/backup.json"},
+			[]string{"x/sync/backup.json", "y/sync/backup.json"},
+		},
+		{"same file", []string{"/a/vault.json", "/a/vault.json"}, []string{"a/vault.json", "a/vault.json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := make([]Source, 0, len(tt.files))
+			for _, f := range tt.files {
+				sources = append(sources, Source{File: filepath.FromSlash(f)})
+			}
+
+			setNames(sources)
+
+			for i, s := range sources {
+				if want := filepath.FromSlash(tt.want[i]); s.Name() != want {
+					t.Errorf("Source.Name() = %q, want %q", s.Name(), want)
+				}
+			}
+		})
+	}
+}
@@ -2,13 +2,20 @@ package input
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"golang.org/x/term"
 )
 
+var errInvalidChoice = errors.New("invalid choice")
+
 // Prompts a question with hidden input. For testing, a default answer
 // can be provided and will be returned as is (only the first list item)
 func Hidden(question string, defaults ...[]byte) ([]byte, error) {
@@ -26,7 +33,10 @@ func Hidden(question string, defaults ...[]byte) ([]byte, error) {
 	return term.ReadPassword(fd)
 }
 
-// Returns piped input
+// shared scanner, so that subsequent calls read subsequent lines.
+var stdin *bufio.Scanner
+
+// Returns the next line of piped input
 func Stdin() ([]byte, error) {
 
 	fi, err := os.Stdin.Stat()
@@ -38,10 +48,92 @@ func Stdin() ([]byte, error) {
 		return nil, errors.New("stdin: no input provided")
 	}
 
-	s := bufio.NewScanner(bufio.NewReader(os.Stdin))
-	if s.Scan(); s.Err() != nil {
-		return nil, s.Err()
+	if stdin == nil {
+		stdin = bufio.NewScanner(bufio.NewReader(os.Stdin))
+	}
+
+	if !stdin.Scan() {
+		if stdin.Err() != nil {
+			return nil, stdin.Err()
+		}
+		return nil, errors.New("stdin: no input left")
+	}
+
+	return bytes.Clone(stdin.Bytes()), nil
+}
+
+// Prints a numbered list of options to stderr and asks for a choice on the
+// terminal, even if stdin is piped. Returns the index of the chosen option.
+func Choose(question string, options []string) (int, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return 0, err
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+
+	return choose(tty, os.Stderr, question, options)
+}
+
+// Asks a yes/no question on the terminal, even if stdin is piped. Anything
+// but "y" or "yes" is a no.
+func Confirm(question string) (bool, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return false, err
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+
+	return confirm(tty, os.Stderr, question)
+}
+
+// Returns stdin if it is a terminal, otherwise the opened terminal device.
+func openTTY() (*os.File, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return os.Stdin, nil
+	}
+
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+
+	return os.Open(name)
+}
+
+func choose(r io.Reader, w io.Writer, question string, options []string) (int, error) {
+	for i, o := range options {
+		fmt.Fprintf(w, "%3d) %s\n", i+1, o)
+	}
+	fmt.Fprintf(w, "%s", question)
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || n < 1 || n > len(options) {
+		return 0, fmt.Errorf("%w: %q", errInvalidChoice, strings.TrimSpace(line))
 	}
 
-	return s.Bytes(), nil
+	return n - 1, nil
+}
+
+func confirm(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N] ", question)
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
 }
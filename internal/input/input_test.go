@@ -1,7 +1,9 @@
 package input
 
 import (
+	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -31,3 +33,69 @@ func TestAskHidden(t *testing.T) {
 		})
 	}
 }
+
+func TestChoose(t *testing.T) {
+	options := []string{"GitHub:alice", "GitLab:alice"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"first", "1\n", 0, false},
+		{"last, no newline", " 2 ", 1, false},
+		{"out of range", "3\n", 0, true},
+		{"zero", "0\n", 0, true},
+		{"not a number", "github\n", 0, true},
+		{"empty", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := choose(strings.NewReader(tt.input), &out, "Entry: ", options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("choose() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("choose() = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(out.String(), "  2) GitLab:alice") {
+				t.Errorf("choose() output = %q, missing options", out.String())
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{"yes", "y\n", true, false},
+		{"yes, long", " YES\n", true, false},
+		{"no", "n\n", false, false},
+		{"default", "\n", false, false},
+		{"anything else", "sure\n", false, false},
+		{"no input", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := confirm(strings.NewReader(tt.input), &out, "Remove?")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("confirm() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("confirm() = %v, want %v", got, tt.want)
+			}
+			if out.String() != "Remove? [y/N] " {
+				t.Errorf("confirm() output = %q", out.String())
+			}
+		})
+	}
+}
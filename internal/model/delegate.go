@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"charm.land/bubbles/v2/list"
@@ -10,6 +11,14 @@ import (
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 )
 
+// Countdown thresholds in seconds. The token turns yellow at warnAt and
+// red at alertAt seconds left. The next token is shown from warnAt on, or
+// from copy_next_below on if that is greater.
+const (
+	warnAt  = 10
+	alertAt = 5
+)
+
 type itemDelegate struct {
 	style *appStyle
 	state *appState
@@ -20,11 +29,26 @@ func (d itemDelegate) Spacing() int                        { return 0 }
 func (d itemDelegate) Update(tea.Msg, *list.Model) tea.Cmd { return nil }
 
 func (d itemDelegate) Render(w io.Writer, m list.Model, idx int, li list.Item) {
+	if s, ok := li.(section); ok {
+		fmt.Fprint(w, d.style.section.Render(s.title()))
+		return
+	}
+
 	entry, _ := li.(vaults.Entry)
 	text := d.style.listItem.Render(entry.Title())
 
+	vault := ""
+	if d.state.showVaults && entry.Vault != "" {
+		vault = d.style.vault.Render(fmt.Sprintf(" [%s]", entry.Vault))
+	}
+
+	tags := ""
+	if len(entry.Tags) > 0 {
+		tags = d.style.tags.Render(" #" + strings.Join(entry.Tags, " #"))
+	}
+
 	if idx != m.Index() {
-		fmt.Fprint(w, text)
+		fmt.Fprint(w, text+tags+vault)
 		return
 	}
 
@@ -32,18 +56,17 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, idx int, li list.Item) {
 	until := max(otp.exp-time.Now().Unix(), 0)
 
 	bgColor, fgColor := green, white
-	if until <= 10 && until > 5 {
-		bgColor, fgColor = yellow, black
-	}
+	if !entry.IsCounterBased() {
+		if until <= warnAt && until > alertAt {
+			bgColor, fgColor = yellow, black
+		}
 
-	if until <= 5 {
-		bgColor = red
+		if until <= alertAt {
+			bgColor = red
+		}
 	}
 
-	formatted := "*** ***"
-	if d.state.showToken {
-		formatted = fmt.Sprintf("%s %s", otp.token[:3], otp.token[3:])
-	}
+	formatted := formatToken(otp.token, !d.state.showToken)
 
 	item := d.style.activeItem.BorderForeground(bgColor).Render(entry.Title())
 	if d.state.showUsernames {
@@ -51,12 +74,41 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, idx int, li list.Item) {
 		item = fmt.Sprintf("%s%s", item, user)
 	}
 
+	status := fmt.Sprintf("%vs", until)
+	if entry.IsCounterBased() {
+		status = fmt.Sprintf("#%d", entry.Counter)
+	}
+
+	// the token might expire while it is typed.
+	next := ""
+	if d.state.showNext(until) {
+		next = d.style.next.Render(" next " + formatToken(otp.next, !d.state.showToken))
+	}
+
 	text = fmt.Sprintf(
-		"%s%s %s",
+		"%s%s %s%s%s%s",
 		item,
 		d.style.token.Background(bgColor).Foreground(fgColor).Render(formatted),
-		d.style.until.Foreground(bgColor).Render(fmt.Sprintf("%vs", until)),
+		d.style.until.Foreground(bgColor).Render(status),
+		next,
+		tags,
+		vault,
 	)
 
 	fmt.Fprint(w, text)
 }
+
+// Formats a token for display. Numeric tokens are split after the third
+// digit, anything else (i.e. steam codes) is shown as is. If masked is
+// set, each character is replaced by an asterisk.
+func formatToken(token string, masked bool) string {
+	if masked {
+		token = strings.Repeat("*", len(token))
+	}
+
+	if len(token) < 6 || strings.Trim(token, "0123456789*") != "" {
+		return token
+	}
+
+	return fmt.Sprintf("%s %s", token[:3], token[3:])
+}
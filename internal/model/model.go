@@ -2,7 +2,7 @@ package model
 
 import (
 	"fmt"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"charm.land/bubbles/v2/key"
@@ -19,6 +19,11 @@ import (
 type (
 	Model struct {
 		list           list.Model
+		entries        []vaults.Entry
+		picker         *tagPicker
+		qr             *qrCode
+		title          string
+		height         int
 		state          *appState
 		style          *appStyle
 		cb             *clipboard.Clipboard
@@ -29,12 +34,15 @@ type (
 	appState struct {
 		showToken     bool
 		showUsernames bool
+		showVaults    bool
+		groupByTag    bool
+		copyNextBelow int64
 		currentOTP    *otp
 	}
 
 	otp struct {
-		token string
-		exp   int64
+		token, next string
+		exp         int64
 	}
 
 	tickMsg struct{}
@@ -46,23 +54,32 @@ var (
 )
 
 func New(entries []vaults.Entry, cfg *config.Config) Model {
+	sources := cfg.Sources()
+
 	state := &appState{
 		showToken:     cfg.Options.ShowTokens,
 		showUsernames: cfg.Options.ShowUsernames,
+		showVaults:    len(sources) > 1,
+		groupByTag:    cfg.Options.GroupByTag,
+		copyNextBelow: int64(cfg.Options.CopyNextBelow),
 		currentOTP:    &otp{},
 	}
 
-	items := make([]list.Item, 0)
-	for _, e := range entries {
-		items = append(items, e)
+	style := newThemedStyle(cfg.Theme)
+	names := make([]string, 0, len(sources))
+	for _, src := range sources {
+		names = append(names, src.Name())
 	}
 
-	style := newThemedStyle(cfg.Theme)
-	title := fmt.Sprintf("%s: %s", buildinfo.AppName, filepath.Base(cfg.File))
+	title := fmt.Sprintf("%s: %s", buildinfo.AppName, strings.Join(names, ", "))
 	dlg := &itemDelegate{style, state}
 
 	m := Model{
-		list:           initList(items, dlg, title),
+		list:           initList(dlg),
+		entries:        entries,
+		picker:         newTagPicker(entries),
+		qr:             &qrCode{},
+		title:          title,
 		state:          state,
 		style:          style,
 		cb:             clipboard.New(cfg.ClipboardCmd),
@@ -70,6 +87,7 @@ func New(entries []vaults.Entry, cfg *config.Config) Model {
 		lastActivity:   time.Now(),
 	}
 
+	m.setItems()
 	m.updateToken()
 
 	return m
@@ -87,11 +105,45 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// resets on each keypress
 		m.lastActivity = time.Now()
 
+		if m.picker.active {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			if m.picker.update(msg.String()) {
+				return m, m.setItems()
+			}
+			return m, nil
+		}
+
+		if m.qr.active {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			if err := m.qr.update(msg.String()); err != nil {
+				return m, m.list.NewStatusMessage(fmt.Sprintf("%s QR code: %s", copyErr, err))
+			}
+			return m, nil
+		}
+
 		if m.list.FilterState() == list.Filtering {
 			break
 		}
 
 		switch msg.String() {
+		case "t":
+			if len(m.picker.tags) == 0 {
+				return m, m.list.NewStatusMessage("No tags available")
+			}
+			m.picker.active = true
+			return m, nil
+		case "Q":
+			if entry, ok := m.list.SelectedItem().(vaults.Entry); ok {
+				m.qr.open(entry)
+			}
+			return m, nil
+		case "s":
+			m.state.groupByTag = !m.state.groupByTag
+			return m, m.setItems()
 		case "enter":
 			m.state.showToken = !m.state.showToken
 		case "u":
@@ -102,8 +154,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, m.list.NewStatusMessage(msg)
 			}
 
+			otp, next := m.state.clipboardToken(time.Now().Unix())
 			msg := fmt.Sprintf("%s Token copied to clipboard", copyOK)
-			if err := m.cb.Set([]byte(m.state.currentOTP.token)); err != nil {
+			if next {
+				msg = fmt.Sprintf("%s Next token copied to clipboard", copyOK)
+			}
+
+			if err := m.cb.Set([]byte(otp)); err != nil {
 				msg = fmt.Sprintf("%s %s: %s", copyErr, m.cb.String(), err)
 			}
 
@@ -121,18 +178,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		h, v := m.style.GetFrameSize()
 		m.list.SetSize(msg.Width-h, msg.Height-v)
+		m.height = msg.Height - v
 	}
 
 	var cmd tea.Cmd
 	m.list, cmd = m.list.Update(msg)
 
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		m.skipSection(msg.String() == "up" || msg.String() == "k")
+	}
+
 	m.updateToken() // fixes regression: a fast moving cursor does not update the otp
 
 	return m, cmd
 }
 
 func (m Model) View() tea.View {
-	view := tea.NewView(m.style.Render(m.list.View()))
+	content := m.list.View()
+	if m.picker.active {
+		content = m.picker.view(m.style, m.height)
+	}
+	if m.qr.active {
+		content = m.qr.view(m.style, m.height)
+	}
+
+	view := tea.NewView(m.style.Render(content))
 	view.AltScreen = true
 	view.WindowTitle = m.list.Title
 	return view
@@ -149,9 +219,77 @@ func (m *Model) updateToken() {
 		return
 	}
 
-	token, exp := entry.GenerateTOTP()
+	token, exp := entry.Generate()
 	m.state.currentOTP.token = token
 	m.state.currentOTP.exp = exp
+
+	// counter based entries have no upcoming token, the counter is
+	// increased on use.
+	m.state.currentOTP.next = ""
+	if !entry.IsCounterBased() {
+		m.state.currentOTP.next = entry.GenerateNext()
+	}
+}
+
+// Returns the token to copy at the unix time now, and whether it is the
+// next one. The next token is copied if the current one expires in less
+// than copyNextBelow seconds, as it might expire before it is pasted.
+func (s appState) clipboardToken(now int64) (string, bool) {
+	if s.currentOTP.next != "" && s.currentOTP.exp-now < s.copyNextBelow {
+		return s.currentOTP.next, true
+	}
+	return s.currentOTP.token, false
+}
+
+// Reports whether the next token is shown with until seconds left: from
+// warnAt on, or earlier if copyNextBelow copies it earlier.
+func (s appState) showNext(until int64) bool {
+	return s.currentOTP.next != "" && (until <= warnAt || until < s.copyNextBelow)
+}
+
+// Sets the list items: all entries matching the selected tags, grouped by
+// tag if enabled.
+func (m *Model) setItems() tea.Cmd {
+	entries := make([]vaults.Entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		if m.picker.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+
+	items := make([]list.Item, 0, len(entries))
+	if m.state.groupByTag {
+		items = groupByTag(entries)
+	} else {
+		for _, e := range entries {
+			items = append(items, e)
+		}
+	}
+
+	m.list.Title = m.title
+	if tags := m.picker.selection(); len(tags) > 0 {
+		m.list.Title = fmt.Sprintf("%s #%s", m.title, strings.Join(tags, " #"))
+	}
+
+	m.list.Filter = newFilter(items)
+	cmd := m.list.SetItems(items)
+	m.list.ResetSelected()
+	m.skipSection(false)
+
+	return cmd
+}
+
+// Moves the cursor past a selected section header.
+func (m *Model) skipSection(up bool) {
+	if _, ok := m.list.SelectedItem().(section); !ok {
+		return
+	}
+
+	if up {
+		m.list.CursorUp()
+	} else {
+		m.list.CursorDown()
+	}
 }
 
 func tick() tea.Cmd {
@@ -160,14 +298,17 @@ func tick() tea.Cmd {
 	})
 }
 
-func initList(items []list.Item, delegate *itemDelegate, title string) list.Model {
-	lst := list.New(items, delegate, 0, 0)
+func initList(delegate *itemDelegate) list.Model {
+	lst := list.New(nil, delegate, 0, 0)
 	style := delegate.style
 
 	keys := []key.Binding{
 		key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "toggle token")),
 		key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "toggle usernames")),
 		key.NewBinding(key.WithKeys("c", "y"), key.WithHelp("c/y", "yank to clipboard")),
+		key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "select tags")),
+		key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "toggle sections")),
+		key.NewBinding(key.WithKeys("Q"), key.WithHelp("Q", "show QR code")),
 	}
 
 	lst.FilterInput.Prompt = "Search for: "
@@ -175,7 +316,6 @@ func initList(items []list.Item, delegate *itemDelegate, title string) list.Mode
 	lst.Styles.Filter.Focused.Text = style.filterCursor
 	lst.Styles.Title = style.title
 	lst.InfiniteScrolling = true
-	lst.Title = title
 	lst.AdditionalShortHelpKeys = func() []key.Binding { return keys }
 	lst.AdditionalFullHelpKeys = func() []key.Binding { return keys }
 
@@ -0,0 +1,61 @@
+package model
+
+import "testing"
+
+func TestAppState_clipboardToken(t *testing.T) {
+	const now = 1000
+
+	tests := []struct {
+		name     string
+		below    int64
+		left     int64
+		next     string
+		want     string
+		wantNext bool
+	}{
+		{"disabled", 0, 1, "222222", "111111", false},
+		{"above threshold", 5, 6, "222222", "111111", false},
+		{"at threshold", 5, 5, "222222", "111111", false},
+		{"below threshold", 5, 4, "222222", "222222", true},
+		{"no next token", 5, 4, "", "111111", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := appState{
+				copyNextBelow: tt.below,
+				currentOTP:    &otp{token: "111111", next: tt.next, exp: now + tt.left},
+			}
+
+			got, next := s.clipboardToken(now)
+			if got != tt.want || next != tt.wantNext {
+				t.Errorf("clipboardToken() = %q, %v, want %q, %v", got, next, tt.want, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestAppState_showNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		below int64
+		until int64
+		next  string
+		want  bool
+	}{
+		{"before warnAt", 0, warnAt + 1, "222222", false},
+		{"at warnAt", 0, warnAt, "222222", true},
+		{"copied before warnAt", warnAt + 5, warnAt + 4, "222222", true},
+		{"not yet copied", warnAt + 5, warnAt + 5, "222222", false},
+		{"no next token", 0, 1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := appState{copyNextBelow: tt.below, currentOTP: &otp{next: tt.next}}
+			if got := s.showNext(tt.until); got != tt.want {
+				t.Errorf("showNext(%d) = %v, want %v", tt.until, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/tjblackheart/andcli/v2/internal/qr"
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+// qrCode shows the otpauth URI of an entry as QR code. The code reveals
+// the secret, so it is rendered after a confirmation only.
+type qrCode struct {
+	entry  vaults.Entry
+	code   string // empty until confirmed
+	active bool
+}
+
+func (q *qrCode) open(e vaults.Entry) {
+	q.entry, q.code, q.active = e, "", true
+}
+
+func (q *qrCode) close() {
+	q.entry, q.code, q.active = vaults.Entry{}, "", false
+}
+
+// Handles a key press while the overlay is open. Any key but a confirmation
+// closes it.
+func (q *qrCode) update(key string) error {
+	if q.code != "" || (key != "y" && key != "Y") {
+		q.close()
+		return nil
+	}
+
+	code, err := qr.Render(q.entry.URI())
+	if err != nil {
+		q.close()
+		return err
+	}
+
+	q.code = code
+	return nil
+}
+
+func (q *qrCode) view(style *appStyle, height int) string {
+	name := q.entry.QualifiedName()
+	lines := []string{style.title.Render("QR code"), ""}
+
+	switch {
+	case q.code == "":
+		lines = append(lines,
+			fmt.Sprintf("Show the QR code of %s?", name),
+			"It contains the secret: anyone who sees it can add the account.",
+			"", style.vault.Render("y show • any other key cancel"),
+		)
+	case lipgloss.Height(q.code)+4 > height:
+		lines = append(lines,
+			"The terminal is too small for the QR code.",
+			"", style.vault.Render("any key closes"),
+		)
+	default:
+		lines = append(lines,
+			style.qr.Render(q.code),
+			"", style.vault.Render(name+" • any key closes"),
+		)
+	}
+
+	return strings.Join(lines, "\n")
+}
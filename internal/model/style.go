@@ -3,13 +3,17 @@ package model
 import (
 	"charm.land/lipgloss/v2"
 	"github.com/tjblackheart/andcli/v2/internal/config"
+	"github.com/tjblackheart/andcli/v2/internal/qr"
 )
 
 type appStyle struct {
 	lipgloss.Style
 	title, listItem, activeItem lipgloss.Style
 	username, filterCursor      lipgloss.Style
+	vault, tags, section        lipgloss.Style
 	filterPrompt, token, until  lipgloss.Style
+	next                        lipgloss.Style
+	qr                          lipgloss.Style
 }
 
 var (
@@ -29,10 +33,15 @@ func newStyle() *appStyle {
 		title:        ls.Background(base).Padding(0, 1),
 		listItem:     ls.PaddingLeft(2).Faint(true),
 		username:     ls.Background(grey),
+		vault:        ls.Foreground(base).Faint(true),
+		tags:         ls.Faint(true),
+		section:      ls.Foreground(base).Bold(true),
 		filterPrompt: ls.Foreground(base),
 		filterCursor: ls.Background(base),
 		token:        ls.Bold(true).Padding(0, 1, 0, 1),
 		until:        ls.Bold(true),
+		next:         ls.Faint(true),
+		qr:           qr.Style,
 		activeItem: ls.
 			Padding(0, 1).
 			Bold(true).
@@ -0,0 +1,204 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/list"
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+// untagged is the section of entries without tags. A tag is never empty,
+// so it can't collide with one, not even with a tag named "untagged".
+const untagged section = ""
+
+type (
+	// section is a group header in the list, named by its tag. It can't be
+	// selected and is never part of a filter result.
+	section string
+
+	// tagPicker restricts the list to entries with one of the selected tags.
+	tagPicker struct {
+		tags     []string
+		selected map[string]bool
+		cursor   int
+		active   bool
+	}
+)
+
+func (s section) FilterValue() string { return "" }
+
+// Returns the displayed name of the section.
+func (s section) title() string {
+	if s == untagged {
+		return "untagged"
+	}
+	return string(s)
+}
+
+func newTagPicker(entries []vaults.Entry) *tagPicker {
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for _, t := range e.Tags {
+			seen[t] = true
+		}
+	}
+
+	return &tagPicker{
+		tags:     slices.Sorted(maps.Keys(seen)),
+		selected: make(map[string]bool),
+	}
+}
+
+// Returns the selected tags in display order.
+func (p *tagPicker) selection() []string {
+	tags := make([]string, 0, len(p.selected))
+	for _, t := range p.tags {
+		if p.selected[t] {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// Returns true if the entry matches the current selection.
+func (p *tagPicker) matches(e vaults.Entry) bool {
+	if len(p.selected) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(p.selection(), e.HasTag)
+}
+
+// Handles a key press while the picker is open. Returns true if the
+// selection has been confirmed.
+func (p *tagPicker) update(key string) bool {
+	switch key {
+	case "up", "k":
+		p.cursor = (p.cursor - 1 + len(p.tags)) % len(p.tags)
+	case "down", "j":
+		p.cursor = (p.cursor + 1) % len(p.tags)
+	case "space", " ", "x":
+		t := p.tags[p.cursor]
+		if p.selected[t] {
+			delete(p.selected, t)
+		} else {
+			p.selected[t] = true
+		}
+	case "a":
+		clear(p.selected)
+	case "enter":
+		p.active = false
+		return true
+	case "esc", "q", "t":
+		p.active = false
+	}
+	return false
+}
+
+func (p *tagPicker) view(style *appStyle, height int) string {
+	lines := []string{style.title.Render("Select tags"), ""}
+
+	// keep the cursor visible if there are more tags than lines.
+	rows := max(height-4, 1)
+	start := max(p.cursor-rows+1, 0)
+	end := min(start+rows, len(p.tags))
+
+	for i, t := range p.tags[start:end] {
+		check := "[ ]"
+		if p.selected[t] {
+			check = "[x]"
+		}
+
+		line := fmt.Sprintf("%s %s", check, t)
+		if start+i == p.cursor {
+			lines = append(lines, style.activeItem.BorderForeground(green).Render(line))
+			continue
+		}
+		lines = append(lines, style.listItem.Render(line))
+	}
+
+	help := "↑/↓ move • space toggle • a clear • enter apply • esc cancel"
+	lines = append(lines, "", style.vault.Render(help))
+
+	return strings.Join(lines, "\n")
+}
+
+// Groups entries by their first tag, sorted by tag name. Entries without
+// tags are listed last. If no entry has a tag, there are no sections.
+func groupByTag(entries []vaults.Entry) []list.Item {
+	groups := make(map[section][]vaults.Entry)
+	for _, e := range entries {
+		tag := untagged
+		if len(e.Tags) > 0 {
+			tag = section(e.Tags[0])
+		}
+		groups[tag] = append(groups[tag], e)
+	}
+
+	items := make([]list.Item, 0, len(entries)+len(groups))
+	if _, ok := groups[untagged]; ok && len(groups) == 1 {
+		for _, e := range entries {
+			items = append(items, e)
+		}
+		return items
+	}
+
+	tags := slices.Sorted(maps.Keys(groups))
+	if i := slices.Index(tags, untagged); i >= 0 {
+		tags = append(slices.Delete(tags, i, i+1), untagged)
+	}
+
+	for _, t := range tags {
+		items = append(items, t)
+		for _, e := range groups[t] {
+			items = append(items, e)
+		}
+	}
+
+	return items
+}
+
+// Returns a list filter for the given items. In addition to the fuzzy
+// search, terms like "tag:work" restrict the result to entries with the
+// given tags.
+func newFilter(items []list.Item) list.FilterFunc {
+	return func(s string, _ []string) []list.Rank {
+		tags, term := vaults.ParseFilter(s)
+
+		// entries with all tags and their item index.
+		entries := make([]vaults.Entry, 0, len(items))
+		index := make([]int, 0, len(items))
+		for i, item := range items {
+			e, ok := item.(vaults.Entry)
+			if !ok {
+				continue
+			}
+
+			matches := true
+			for _, t := range tags {
+				matches = matches && e.HasTag(t)
+			}
+
+			if matches {
+				entries = append(entries, e)
+				index = append(index, i)
+			}
+		}
+
+		ranks := make([]list.Rank, 0, len(entries))
+		if term == "" {
+			for _, i := range index {
+				ranks = append(ranks, list.Rank{Index: i})
+			}
+			return ranks
+		}
+
+		for _, m := range vaults.Search(term, entries) {
+			ranks = append(ranks, list.Rank{Index: index[m.Index], MatchedIndexes: m.MatchedIndexes})
+		}
+
+		return ranks
+	}
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"charm.land/bubbles/v2/list"
+	"github.com/tjblackheart/andcli/v2/internal/config"
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+var (
+	github  = totp("GitHub", "work")
+	gitlab  = totp("GitLab", "work/dev", "private")
+	mail    = totp("Mail", "private")
+	shop    = totp("Shop")
+	literal = totp("Literal", "untagged")
+)
+
+// Returns a valid time based entry with the given tags.
+func totp(issuer string, tags ...string) vaults.Entry {
+	return vaults.Entry{
+		Secret:    "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		Issuer:    issuer,
+		Type:      vaults.TOTP,
+		Algorithm: "SHA1",
+		Tags:      tags,
+		Digits:    6,
+		Period:    30,
+	}
+}
+
+func TestGroupByTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []vaults.Entry
+		want    []list.Item
+	}{
+		{"empty", nil, []list.Item{}},
+		{"no tags: no sections", []vaults.Entry{shop, shop}, []list.Item{shop, shop}},
+		{
+			"keeps the entry order in sections",
+			[]vaults.Entry{mail, github, gitlab, shop, github},
+			[]list.Item{section("private"), mail, section("work"), github, github, section("work/dev"), gitlab, untagged, shop},
+		},
+		{
+			"sorted by first tag, untagged last",
+			[]vaults.Entry{shop, mail, github, gitlab},
+			[]list.Item{section("private"), mail, section("work"), github, section("work/dev"), gitlab, untagged, shop},
+		},
+		{
+			"tag named untagged",
+			[]vaults.Entry{shop, literal, github},
+			[]list.Item{section("untagged"), literal, section("work"), github, untagged, shop},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupByTag(tt.entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupByTag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSection_title(t *testing.T) {
+	if got := untagged.title(); got != "untagged" {
+		t.Errorf("section.title() = %q, want %q", got, "untagged")
+	}
+	if got := section("work").title(); got != "work" {
+		t.Errorf("section.title() = %q, want %q", got, "work")
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	items := groupByTag([]vaults.Entry{github, gitlab, mail, shop})
+
+	tests := []struct {
+		name   string
+		filter string
+		want   []string
+	}{
+		{"term", "git", []string{"GitHub", "GitLab"}},
+		{"tag", "tag:work", []string{"GitHub", "GitLab"}},
+		{"subgroup", "tag:work/dev", []string{"GitLab"}},
+		{"all tags", "tag:work tag:private", []string{"GitLab"}},
+		{"tag and term", "tag:private mail", []string{"Mail"}},
+		{"no match", "tag:none", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, r := range newFilter(items)(tt.filter, nil) {
+				e, ok := items[r.Index].(vaults.Entry)
+				if !ok {
+					t.Fatalf("filter matched section %v", items[r.Index])
+				}
+				got = append(got, e.Issuer)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newFilter(%q) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagPicker(t *testing.T) {
+	p := newTagPicker([]vaults.Entry{github, gitlab, mail, shop})
+	p.active = true
+
+	if want := []string{"private", "work", "work/dev"}; !reflect.DeepEqual(p.tags, want) {
+		t.Fatalf("newTagPicker() tags = %v, want %v", p.tags, want)
+	}
+
+	// wraps around to the last tag, selects it and the first one.
+	for _, key := range []string{"k", "space", "j", "x"} {
+		if p.update(key) {
+			t.Fatalf("update(%q) confirmed the selection", key)
+		}
+	}
+
+	if want := []string{"private", "work/dev"}; !reflect.DeepEqual(p.selection(), want) {
+		t.Errorf("selection() = %v, want %v", p.selection(), want)
+	}
+
+	for e, want := range map[string]bool{"GitHub": false, "GitLab": true, "Mail": true, "Shop": false} {
+		entry := map[string]vaults.Entry{"GitHub": github, "GitLab": gitlab, "Mail": mail, "Shop": shop}[e]
+		if got := p.matches(entry); got != want {
+			t.Errorf("matches(%s) = %v, want %v", e, got, want)
+		}
+	}
+
+	if !p.update("enter") || p.active {
+		t.Error("update(enter) did not confirm and close the picker")
+	}
+
+	p.update("a")
+	if len(p.selection()) != 0 || !p.matches(shop) {
+		t.Errorf("update(a) did not clear the selection: %v", p.selection())
+	}
+}
+
+func TestModel_skipSection(t *testing.T) {
+	m := newTestModel([]vaults.Entry{github, mail, shop}, true)
+	items := m.list.Items()
+
+	tests := []struct {
+		name   string
+		cursor int
+		up     bool
+		want   list.Item
+	}{
+		{"first section", 0, false, mail},
+		{"section down", 2, false, github},
+		{"section up", 2, true, mail},
+		{"untagged section down", 4, false, shop},
+		{"entry stays", 3, false, github},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.list.Select(tt.cursor)
+			m.skipSection(tt.up)
+			if got := m.list.SelectedItem(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("skipSection() selected %v (%v), want %v", got, items, tt.want)
+			}
+		})
+	}
+}
+
+// Returns a model with a fixed size, which is needed for a selection.
+func newTestModel(entries []vaults.Entry, grouped bool) Model {
+	cfg := &config.Config{
+		Options: &config.Opts{GroupByTag: grouped},
+		Theme:   &config.DefaultTheme,
+	}
+
+	m := New(entries, cfg)
+	m.list.SetSize(80, 40)
+	m.setItems()
+
+	return m
+}
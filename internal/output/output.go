@@ -0,0 +1,184 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+// Format is an output format for query results.
+type Format string
+
+// Supported output formats.
+const (
+	TEXT  Format = "text"
+	JSON  Format = "json"
+	TOKEN Format = "token"
+	ENV   Format = "env"
+)
+
+// result is the JSON representation of a generated token. Time based
+// entries have an expiration time and period, counter based entries a
+// counter.
+type result struct {
+	Issuer    string     `json:"issuer"`
+	Label     string     `json:"label"`
+	Type      string     `json:"type"`
+	Token     string     `json:"token"`
+	NextToken string     `json:"next_token"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Period    int        `json:"period,omitempty"`
+	Counter   *int       `json:"counter,omitempty"`
+}
+
+// item is the JSON representation of a listed entry. It never contains
+// secrets.
+type item struct {
+	Issuer    string   `json:"issuer"`
+	Label     string   `json:"label"`
+	Tags      []string `json:"tags"`
+	Type      string   `json:"type"`
+	Digits    int      `json:"digits"`
+	Period    int      `json:"period,omitempty"`
+	Algorithm string   `json:"algorithm"`
+	Vault     string   `json:"vault,omitempty"`
+}
+
+// Returns all supported formats.
+func Formats() []Format {
+	return []Format{TEXT, JSON, TOKEN, ENV}
+}
+
+// Returns all supported formats as a comma separated string.
+func StrFormats() string {
+	formats := make([]string, 0)
+	for _, f := range Formats() {
+		formats = append(formats, string(f))
+	}
+	return strings.Join(formats, ", ")
+}
+
+// Write generates the current token of the entry and writes it in the
+// given format. If newline is false, the trailing newline is omitted.
+func Write(w io.Writer, e vaults.Entry, f Format, newline bool) error {
+	token, exp := e.Generate()
+
+	var out string
+	switch f {
+	case TEXT, "":
+		out = fmt.Sprintf("%s %s %ds", e.Issuer, token, max(exp-time.Now().Unix(), 0))
+		if e.IsCounterBased() {
+			out = fmt.Sprintf("%s %s #%d", e.Issuer, token, e.Counter)
+		}
+	case TOKEN:
+		out = token
+	case ENV:
+		out = fmt.Sprintf("ANDCLI_TOKEN=%s", token)
+	case JSON:
+		r := result{
+			Issuer:    e.Title(),
+			Label:     e.Description(),
+			Type:      e.Type,
+			Token:     token,
+			NextToken: e.GenerateNext(),
+		}
+
+		if e.IsCounterBased() {
+			r.Counter = &e.Counter
+		} else {
+			expiresAt := time.Unix(exp, 0).UTC()
+			r.ExpiresAt, r.Period = &expiresAt, e.Period
+		}
+
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		out = string(b)
+	default:
+		return fmt.Errorf("output format %q: not supported", f)
+	}
+
+	if newline {
+		out += "\n"
+	}
+
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// List writes the entries as table (TEXT) or as JSON array, without secrets
+// or tokens. Other formats are not supported.
+func List(w io.Writer, entries []vaults.Entry, f Format) error {
+	switch f {
+	case TEXT, "":
+		return table(w, entries)
+	case JSON:
+		items := make([]item, 0, len(entries))
+		for _, e := range entries {
+			items = append(items, item{
+				Issuer:    e.Title(),
+				Label:     e.Description(),
+				Tags:      append(make([]string, 0), e.Tags...),
+				Type:      e.Type,
+				Digits:    e.Digits,
+				Period:    e.Period,
+				Algorithm: e.Algorithm,
+				Vault:     e.Vault,
+			})
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	default:
+		return fmt.Errorf("output format %q: not supported for lists", f)
+	}
+}
+
+// Writes the entries as aligned table. The vault column is only shown if
+// there are entries of multiple vaults.
+func table(w io.Writer, entries []vaults.Entry) error {
+	showVaults := false
+	for _, e := range entries {
+		showVaults = showVaults || e.Vault != ""
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := "ISSUER\tLABEL\tTAGS\tTYPE\tDIGITS\tPERIOD\tALGORITHM"
+	if showVaults {
+		header += "\tVAULT"
+	}
+	fmt.Fprintln(tw, header)
+
+	for _, e := range entries {
+		period := "-"
+		if !e.IsCounterBased() {
+			period = strconv.Itoa(e.Period)
+		}
+
+		row := []string{
+			e.Title(),
+			e.Description(),
+			strings.Join(e.Tags, ","),
+			e.Type,
+			strconv.Itoa(e.Digits),
+			period,
+			e.Algorithm,
+		}
+		if showVaults {
+			row = append(row, e.Vault)
+		}
+
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,170 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestWrite(t *testing.T) {
+	hotp := vaults.Entry{
+		Secret:  "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		Issuer:  "issuer",
+		Label:   "issuer:user",
+		Type:    "HOTP",
+		Digits:  6,
+		Counter: 1,
+	}
+
+	tests := []struct {
+		name    string
+		format  Format
+		newline bool
+		want    string
+		fails   bool
+	}{
+		{"text", TEXT, true, "issuer 287082 #1\n", false},
+		{"text: default", "", true, "issuer 287082 #1\n", false},
+		{"token", TOKEN, true, "287082\n", false},
+		{"token: no newline", TOKEN, false, "287082", false},
+		{"env", ENV, true, "ANDCLI_TOKEN=287082\n", false},
+		{
+			"json",
+			JSON,
+			true,
+			`{"issuer":"issuer","label":"user","type":"HOTP","token":"287082","next_token":"359152","counter":1}` + "\n",
+			false,
+		},
+		{"fails: unknown format", Format("xml"), true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Write(&buf, hotp, tt.format, tt.newline)
+			if (err != nil) != tt.fails {
+				t.Fatalf("Write() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Write() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite_timeBased(t *testing.T) {
+	totp := vaults.Entry{
+		Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		Issuer: "issuer",
+		Type:   "TOTP",
+		Digits: 6,
+		Period: 30,
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, totp, TEXT, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`^issuer \d{6} \d+s\n$`).MatchString(buf.String()) {
+		t.Errorf("Write() text = %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, totp, JSON, true); err != nil {
+		t.Fatal(err)
+	}
+
+	var r result
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.ExpiresAt == nil || r.ExpiresAt.Before(time.Now()) || r.Period != 30 || r.Counter != nil {
+		t.Errorf("Write() json = %s", buf.String())
+	}
+}
+
+func TestList(t *testing.T) {
+	entries := []vaults.Entry{
+		{Secret: "SECRET1", Issuer: "GitHub", Label: "alice", Tags: []string{"work", "dev"}, Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+		{Secret: "SECRET2", Issuer: "Counter", Label: "bob", Type: "HOTP", Algorithm: "SHA256", Digits: 8, Counter: 3},
+	}
+
+	tests := []struct {
+		name    string
+		entries []vaults.Entry
+		format  Format
+		want    string
+		fails   bool
+	}{
+		{
+			"table",
+			entries,
+			TEXT,
+			"ISSUER   LABEL  TAGS      TYPE  DIGITS  PERIOD  ALGORITHM\n" +
+				"GitHub   alice  work,dev  TOTP  6       30      SHA1\n" +
+				"Counter  bob              HOTP  8       -       SHA256\n",
+			false,
+		},
+		{
+			"table: vaults",
+			[]vaults.Entry{{Issuer: "GitHub", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30, Vault: "vault.json"}},
+			TEXT,
+			"ISSUER  LABEL  TAGS  TYPE  DIGITS  PERIOD  ALGORITHM  VAULT\n" +
+				"GitHub               TOTP  6       30      SHA1       vault.json\n",
+			false,
+		},
+		{
+			"json",
+			entries,
+			JSON,
+			`[
+  {
+    "issuer": "GitHub",
+    "label": "alice",
+    "tags": [
+      "work",
+      "dev"
+    ],
+    "type": "TOTP",
+    "digits": 6,
+    "period": 30,
+    "algorithm": "SHA1"
+  },
+  {
+    "issuer": "Counter",
+    "label": "bob",
+    "tags": [],
+    "type": "HOTP",
+    "digits": 8,
+    "algorithm": "SHA256"
+  }
+]
+`,
+			false,
+		},
+		{"json: empty", nil, JSON, "[]\n", false},
+		{"fails: token format", entries, TOKEN, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := List(&buf, tt.entries, tt.format)
+			if (err != nil) != tt.fails {
+				t.Fatalf("List() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("List() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if bytes.Contains(buf.Bytes(), []byte("SECRET")) {
+				t.Error("List() contains a secret")
+			}
+		})
+	}
+}
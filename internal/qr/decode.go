@@ -0,0 +1,60 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// ErrNotFound is returned if an image contains no readable QR code.
+var ErrNotFound = errors.New("no readable QR code found")
+
+// Decode returns the content of the QR code in a PNG or JPEG image. Light
+// codes on dark background, like in screenshots of dark themes, are
+// decoded as well.
+func Decode(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("image: %w", err)
+	}
+
+	src := gozxing.NewLuminanceSourceFromImage(img)
+	hints := map[gozxing.DecodeHintType]any{gozxing.DecodeHintType_TRY_HARDER: true}
+
+	for _, s := range []gozxing.LuminanceSource{src, src.Invert()} {
+		bmp, err := gozxing.NewBinaryBitmap(gozxing.NewHybridBinarizer(s))
+		if err != nil {
+			return "", err
+		}
+
+		result, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+		if err == nil {
+			return result.GetText(), nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+// DecodeFile returns the content of the QR code in the image file.
+func DecodeFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	s, err := Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,115 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const testURI = "otpauth://totp/GitHub:alice?issuer=GitHub&secret=GEZDGNBVGY3TQOJQ"
+
+func TestDecode(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 100, 100))
+	for i := range blank.Pix {
+		blank.Pix[i] = 255
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+		err   error
+	}{
+		{"png", encodePNG(t, code(t, testURI, false)), testURI, nil},
+		{"jpeg", encodeJPEG(t, code(t, testURI, false)), testURI, nil},
+		{"png: inverted", encodePNG(t, code(t, testURI, true)), testURI, nil},
+		{"fails: no code", encodePNG(t, blank), "", ErrNotFound},
+		{"fails: no image", []byte(testURI), "", image.ErrFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(bytes.NewReader(tt.input))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Decode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "code.png")
+	if err := os.WriteFile(filename, encodePNG(t, code(t, testURI, false)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecodeFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != testURI {
+		t.Errorf("DecodeFile() = %q, want %q", got, testURI)
+	}
+
+	if _, err := DecodeFile(filename + ".missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("DecodeFile() error = %v, want %v", err, os.ErrNotExist)
+	}
+
+	if err := os.WriteFile(filename, []byte("no image"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeFile(filename); err == nil || !strings.HasPrefix(err.Error(), filename) {
+		t.Errorf("DecodeFile() error = %v, want prefix %q", err, filename)
+	}
+}
+
+// Returns the QR code of s as image, light on dark if inverted.
+func code(t *testing.T, s string, inverted bool) image.Image {
+	t.Helper()
+
+	m, err := qrcode.NewQRCodeWriter().Encode(s, gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewGray(m.Bounds())
+	for y := range m.GetHeight() {
+		for x := range m.GetWidth() {
+			img.SetGray(x, y, module(m.Get(x, y) != inverted))
+		}
+	}
+
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 75}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
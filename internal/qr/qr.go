@@ -0,0 +1,59 @@
+// Package qr renders QR codes as text and decodes them from images.
+package qr
+
+import (
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/makiuchi-d/gozxing/qrcode/decoder"
+)
+
+// quietZone is the light border around the code, in modules. The standard
+// asks for 4, but 2 are enough for phone cameras and save lines.
+const quietZone = 2
+
+// Style prints rendered codes black on white, whatever the terminal theme,
+// as scanners expect dark modules on a light background.
+var Style = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#000000")).
+	Background(lipgloss.Color("#ffffff"))
+
+// Render returns the QR code of s as lines of Unicode half blocks, two
+// modules per character. Dark modules are drawn as blocks, so the result
+// has to be printed dark on light, e.g. with Style.
+func Render(s string) (string, error) {
+	hints := map[gozxing.EncodeHintType]any{
+		gozxing.EncodeHintType_ERROR_CORRECTION: decoder.ErrorCorrectionLevel_L,
+		gozxing.EncodeHintType_MARGIN:           quietZone,
+	}
+
+	m, err := qrcode.NewQRCodeWriter().Encode(s, gozxing.BarcodeFormat_QR_CODE, 0, 0, hints)
+	if err != nil {
+		return "", err
+	}
+
+	// the height is odd, the last line is completed with light modules.
+	dark := func(x, y int) bool { return y < m.GetHeight() && m.Get(x, y) }
+
+	lines := make([]string, 0, (m.GetHeight()+1)/2)
+	for y := 0; y < m.GetHeight(); y += 2 {
+		var b strings.Builder
+		for x := 0; x < m.GetWidth(); x++ {
+			switch top, bottom := dark(x, y), dark(x, y+1); {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		lines = append(lines, b.String())
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,80 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		fails bool
+	}{
+		{"uri", "otpauth://totp/GitHub:alice?issuer=GitHub&secret=GEZDGNBVGY3TQOJQ", false},
+		{"long uri", "otpauth://totp/ACME:john?secret=" + strings.Repeat("JBSWY3DPEHPK3PXP", 8), false},
+		{"fails: empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.input)
+			if (err != nil) != tt.fails {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.fails)
+			}
+			if tt.fails {
+				return
+			}
+
+			if decoded := decode(t, got); decoded != tt.input {
+				t.Errorf("Render(): decoded %q, want %q", decoded, tt.input)
+			}
+		})
+	}
+}
+
+// Draws the half blocks as image, 4 pixels per module, and decodes it.
+func decode(t *testing.T, s string) string {
+	t.Helper()
+
+	const scale = 4
+	lines := strings.Split(s, "\n")
+	width := utf8.RuneCountInString(lines[0])
+
+	img := image.NewGray(image.Rect(0, 0, width*scale, len(lines)*2*scale))
+	for y, line := range lines {
+		for x, r := range []rune(line) {
+			top, bottom := r == '█' || r == '▀', r == '█' || r == '▄'
+			for i := range scale * scale {
+				dx, dy := x*scale+i%scale, y*2*scale+i/scale
+				img.SetGray(dx, dy, module(top))
+				img.SetGray(dx, dy+scale, module(bottom))
+			}
+		}
+	}
+
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result.GetText()
+}
+
+func module(dark bool) color.Gray {
+	if dark {
+		return color.Gray{Y: 0}
+	}
+	return color.Gray{Y: 255}
+}
@@ -1,14 +1,17 @@
 package aegis
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
@@ -17,7 +20,7 @@ import (
 
 const vaultType = vaults.AEGIS
 
-var _ vaults.Vault = &aegis{}
+var _ vaults.Writable = &aegis{}
 
 type (
 	aegis struct {
@@ -35,14 +38,19 @@ type (
 				Nonce, Tag string
 			}
 		}
-		DB string
+		DB json.RawMessage
 		//
-		db db
+		db       db
+		filename string
+		key      []byte          // master key, nil for plain exports
+		raw      json.RawMessage // the file, to keep unknown fields on save
 	}
 
 	db struct {
 		Version int
 		Entries []entry
+		Groups  []group
+		raw     json.RawMessage
 	}
 
 	entry struct {
@@ -50,24 +58,38 @@ type (
 		Issuer, Note, Icon string
 		IconMime           string `json:"icon_mime"`
 		Info               info
+		Group              string   // db version < 3
+		Groups             []string // group uuids
+		raw                json.RawMessage
+	}
+
+	group struct {
+		UUID string `json:"uuid"`
+		Name string `json:"name"`
 	}
 
 	info struct {
-		Secret, Algo   string
-		Digits, Period int
+		Secret  string `json:"secret"`
+		Algo    string `json:"algo"`
+		Digits  int    `json:"digits"`
+		Period  int    `json:"period"`
+		Counter int    `json:"counter"`
+		Pin     string `json:"pin,omitempty"`
+		raw     json.RawMessage
 	}
 )
 
 func Open(filename string, pass []byte) (vaults.Vault, error) {
-	var v aegis
-
-	b, err := os.ReadFile(filename)
+	v, err := read(filename)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	if err := json.Unmarshal(b, &v); err != nil {
-		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	if !v.encrypted() {
+		if err := v.db.parse(v.DB); err != nil {
+			return nil, fmt.Errorf("%s: %w", vaultType, err)
+		}
+		return &v, nil
 	}
 
 	key, err := v.masterKeyFromPass(pass)
@@ -75,38 +97,312 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	b, err = v.decryptDB(key)
+	b, err := v.decryptDB(key)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	if err := json.Unmarshal(b, &v.db); err != nil {
+	if err := v.db.parse(b); err != nil {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	return v, nil
+	v.key = key
+
+	return &v, nil
+}
+
+// IsEncrypted reports whether the given file is an encrypted export.
+func IsEncrypted(filename string) (bool, error) {
+	v, err := read(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return v.encrypted(), nil
 }
 
 func (v aegis) Entries() []vaults.Entry {
-	entries := make([]vaults.Entry, 0)
+	entries, _ := v.entries()
+	return entries
+}
+
+// Returns the valid entries and their index in the db.
+func (v aegis) entries() ([]vaults.Entry, []int) {
+	entries, indexes := make([]vaults.Entry, 0), make([]int, 0)
+
+	groups := make(map[string]string)
+	for _, g := range v.db.Groups {
+		groups[g.UUID] = g.Name
+	}
+
+	for i, e := range v.db.Entries {
+		var tags []string
+		if e.Group != "" {
+			tags = append(tags, e.Group)
+		}
+		for _, id := range e.Groups {
+			if name, ok := groups[id]; ok {
+				tags = append(tags, name)
+			}
+		}
 
-	for _, e := range v.db.Entries {
 		entry := vaults.Entry{
 			Secret:    e.Info.Secret,
+			Pin:       e.Info.Pin,
 			Issuer:    e.Issuer,
 			Label:     e.Name,
 			Digits:    e.Info.Digits,
 			Type:      strings.ToUpper(e.Type),
 			Algorithm: e.Info.Algo,
 			Period:    e.Info.Period,
+			Counter:   e.Info.Counter,
+			Tags:      tags,
 		}
 
 		if err := entry.SanitizeAndValidate(); err == nil {
 			entries = append(entries, entry)
+			indexes = append(indexes, i)
 		}
 	}
 
-	return entries
+	return entries, indexes
+}
+
+func (v *aegis) Add(e vaults.Entry) error {
+	var dst entry
+	if err := v.set(&dst, e); err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	v.db.Entries = append(v.db.Entries, dst)
+	return nil
+}
+
+func (v *aegis) Update(i int, e vaults.Entry) error {
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+
+	if err := v.set(&v.db.Entries[j], e); err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return nil
+}
+
+func (v *aegis) Remove(i int) error {
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+
+	v.db.Entries = slices.Delete(v.db.Entries, j, j+1)
+	return nil
+}
+
+// Save writes the vault back to its file. Encrypted vaults are encrypted
+// with the existing master key, so all key slots and their salts stay valid.
+func (v *aegis) Save() error {
+	b, err := v.encode()
+	if err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	if err := vaults.WriteFile(v.filename, b); err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return nil
+}
+
+// Export returns the entries as new vault file. With a password, the vault
+// is encrypted with a new master key in a single password slot, otherwise
+// it is a plain export.
+func Export(entries []vaults.Entry, pass []byte) ([]byte, error) {
+	v := &aegis{
+		db:  db{Version: 3, raw: json.RawMessage(`{"version":3}`)},
+		raw: json.RawMessage(`{"version":1,"header":{"slots":null,"params":null}}`),
+	}
+
+	if len(pass) > 0 {
+		if err := v.newSlot(pass); err != nil {
+			return nil, fmt.Errorf("%s: %w", vaultType, err)
+		}
+	}
+
+	for _, e := range entries {
+		// Add prefixes its errors with the vault type.
+		if err := v.Add(e); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.QualifiedName(), err)
+		}
+	}
+
+	b, err := v.encode()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return b, nil
+}
+
+// Returns the vault as indented JSON, like the app writes it.
+func (v *aegis) encode() ([]byte, error) {
+	entries := make([]json.RawMessage, 0, len(v.db.Entries))
+	for _, e := range v.db.Entries {
+		entries = append(entries, e.raw)
+	}
+
+	fields := map[string]any{"entries": entries}
+	if v.db.Version >= 3 {
+		fields["groups"] = append(make([]group, 0), v.db.Groups...)
+	}
+
+	db, err := vaults.Patch(v.db.raw, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	fields = map[string]any{"db": db}
+	if v.encrypted() {
+		var file struct{ Header json.RawMessage }
+		if err := json.Unmarshal(v.raw, &file); err != nil {
+			return nil, err
+		}
+
+		enc, params, err := v.encryptDB(db)
+		if err != nil {
+			return nil, err
+		}
+
+		header, err := vaults.Patch(file.Header, map[string]any{"params": params})
+		if err != nil {
+			return nil, err
+		}
+
+		fields["header"], fields["db"] = header, enc
+	}
+
+	b, err := vaults.Patch(v.raw, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "    "); err != nil {
+		return nil, err
+	}
+
+	v.raw = b
+	return buf.Bytes(), nil
+}
+
+// Returns the db index of the i-th entry of Entries().
+func (v aegis) index(i int) (int, error) {
+	_, indexes := v.entries()
+	if i < 0 || i >= len(indexes) {
+		return 0, fmt.Errorf("%s: %w", vaultType, vaults.ErrNoEntry)
+	}
+	return indexes[i], nil
+}
+
+// Applies e to the db entry dst. Fields unknown to andcli are kept.
+func (v *aegis) set(dst *entry, e vaults.Entry) error {
+	if err := e.SanitizeAndValidate(); err != nil {
+		return err
+	}
+
+	// aegis stores the mOTP secret as base32, not as hex.
+	secret, err := e.Base32Secret()
+	if err != nil {
+		return err
+	}
+	e.Secret, e.HexSecret = secret, false
+
+	if dst.UUID == "" {
+		dst.UUID = vaults.NewUUID()
+	}
+
+	dst.Type, dst.Name, dst.Issuer = strings.ToLower(e.Type), e.Label, e.Issuer
+	infoFields := map[string]any{
+		"secret":  e.Secret,
+		"algo":    e.Algorithm,
+		"digits":  e.Digits,
+		"period":  e.Period,
+		"counter": e.Counter,
+		"pin":     nil,
+	}
+	if e.Pin != "" {
+		infoFields["pin"] = e.Pin
+	}
+
+	rawInfo, err := vaults.Patch(dst.Info.raw, infoFields)
+	if err != nil {
+		return err
+	}
+
+	dst.Info = info{
+		Secret:  e.Secret,
+		Algo:    e.Algorithm,
+		Digits:  e.Digits,
+		Period:  e.Period,
+		Counter: e.Counter,
+		Pin:     e.Pin,
+		raw:     rawInfo,
+	}
+
+	fields := map[string]any{
+		"type":   dst.Type,
+		"uuid":   dst.UUID,
+		"name":   dst.Name,
+		"issuer": dst.Issuer,
+		"info":   rawInfo,
+	}
+
+	if dst.raw == nil {
+		fields["note"], fields["favorite"] = "", false
+	}
+
+	// db versions before 3 have a single group name per entry.
+	if v.db.Version < 3 {
+		dst.Group, fields["group"] = "", nil
+		if len(e.Tags) > 0 {
+			dst.Group, fields["group"] = e.Tags[0], e.Tags[0]
+		}
+	} else {
+		dst.Groups = v.groupIDs(e.Tags)
+		fields["groups"] = dst.Groups
+	}
+
+	raw, err := vaults.Patch(dst.raw, fields)
+	if err != nil {
+		return err
+	}
+
+	dst.raw = raw
+	return nil
+}
+
+// Returns the group uuids of the given names. Missing groups are created.
+func (v *aegis) groupIDs(names []string) []string {
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(v.db.Groups, func(g group) bool {
+			return strings.EqualFold(g.Name, name)
+		})
+
+		if i < 0 {
+			v.db.Groups = append(v.db.Groups, group{UUID: vaults.NewUUID(), Name: name})
+			i = len(v.db.Groups) - 1
+		}
+
+		ids = append(ids, v.db.Groups[i].UUID)
+	}
+	return ids
+}
+
+// Plain exports have no key slots and the db is stored as an object.
+func (v aegis) encrypted() bool {
+	return v.Header.Slots != nil
 }
 
 func (v aegis) masterKeyFromPass(password []byte) ([]byte, error) {
@@ -182,7 +478,12 @@ func (v aegis) decryptDB(key []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	db, err := base64.StdEncoding.DecodeString(v.DB)
+	var s string
+	if err := json.Unmarshal(v.DB, &s); err != nil {
+		return nil, err
+	}
+
+	db, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
 		return nil, err
 	}
@@ -203,3 +504,136 @@ func (v aegis) decryptDB(key []byte) ([]byte, error) {
 
 	return plain, nil
 }
+
+// Creates a new master key, protected by a password slot with the scrypt
+// parameters of the app.
+func (v *aegis) newSlot(pass []byte) error {
+	v.key = make([]byte, 32)
+	salt := make([]byte, 32)
+	nonce := make([]byte, 12)
+	for _, b := range [][]byte{v.key, salt, nonce} {
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+	}
+
+	n, r, p := 1<<15, 8, 1
+	derivedKey, err := scrypt.Key(pass, salt, n, r, p, 32)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	b := gcm.Seal(nil, nonce, v.key, nil)
+	k := len(b) - gcm.Overhead()
+
+	slot := map[string]any{
+		"type":       1,
+		"uuid":       vaults.NewUUID(),
+		"key":        hex.EncodeToString(b[:k]),
+		"key_params": map[string]string{"nonce": hex.EncodeToString(nonce), "tag": hex.EncodeToString(b[k:])},
+		"n":          n,
+		"r":          r,
+		"p":          p,
+		"salt":       hex.EncodeToString(salt),
+		"repaired":   true,
+		"is_backup":  false,
+	}
+
+	header, err := json.Marshal(map[string]any{"slots": []any{slot}, "params": nil})
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(header, &v.Header); err != nil {
+		return err
+	}
+
+	v.raw, err = vaults.Patch(v.raw, map[string]any{"header": json.RawMessage(header)})
+	return err
+}
+
+// Encrypts the db with the master key and a new nonce. Returns the
+// encrypted db and the header params.
+func (v aegis) encryptDB(plain []byte) (string, map[string]string, error) {
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+
+	b := gcm.Seal(nil, nonce, plain, nil)
+	n := len(b) - gcm.Overhead()
+
+	params := map[string]string{
+		"nonce": hex.EncodeToString(nonce),
+		"tag":   hex.EncodeToString(b[n:]),
+	}
+
+	return base64.StdEncoding.EncodeToString(b[:n]), params, nil
+}
+
+// reads and parses the vault file.
+func read(filename string) (aegis, error) {
+	v := aegis{filename: filename}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, err
+	}
+
+	v.raw = b
+
+	return v, nil
+}
+
+// parses the decrypted or plain db.
+func (d *db) parse(b []byte) error {
+	if err := json.Unmarshal(b, d); err != nil {
+		return err
+	}
+	d.raw = b
+	return nil
+}
+
+// keeps the raw entry, to write back fields unknown to andcli.
+func (e *entry) UnmarshalJSON(b []byte) error {
+	type plain entry
+	if err := json.Unmarshal(b, (*plain)(e)); err != nil {
+		return err
+	}
+	e.raw = bytes.Clone(b)
+	return nil
+}
+
+// keeps the raw info, to write back fields unknown to andcli.
+func (i *info) UnmarshalJSON(b []byte) error {
+	type plain info
+	if err := json.Unmarshal(b, (*plain)(i)); err != nil {
+		return err
+	}
+	i.raw = bytes.Clone(b)
+	return nil
+}
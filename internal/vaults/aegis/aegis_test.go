@@ -1,7 +1,13 @@
 package aegis
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
@@ -15,6 +21,7 @@ func TestOpen(t *testing.T) {
 		fails    bool
 	}{
 		{"decrypts", "testdata/aegis-export-test.json", "andcli-test", false},
+		{"opens plain", "testdata/aegis-export-plain.json", "", false},
 		{"fails: wrong password", "testdata/aegis-export-test.json", "invalid", true},
 		{"fails: invalid file", "testdata/aegis-invalid-file.json", "invalid", true},
 	}
@@ -41,35 +48,303 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/aegis-export-test.json", true, false},
+		{"plain", "testdata/aegis-export-plain.json", false, false},
+		{"fails: invalid file", "testdata/aegis-invalid-file.json", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestEntries(t *testing.T) {
 	tests := []struct {
-		name  string
-		input []entry
-		want  []vaults.Entry
+		name   string
+		input  []entry
+		groups []group
+		want   []vaults.Entry
 	}{
 		{
 			"mitigates missing fields",
 			[]entry{
-				{Issuer: "iss-1", Info: info{Digits: 6, Secret: "secret"}, Type: "TOTP"},
-				{Issuer: "iss-2", Info: info{Digits: 4, Secret: "secret"}, Type: "HOTP"},
-				{Issuer: "iss-3", Info: info{Digits: 0, Secret: "secret", Period: 20}, Type: "TOTP"},
-				{Issuer: "iss-4", Info: info{Digits: 4, Secret: "secret", Algo: "SHA256"}, Type: "TOTP"},
+				{Issuer: "iss-1", Info: info{Digits: 6, Secret: "JBSWY3DP"}, Type: "TOTP"},
+				{Issuer: "iss-2", Info: info{Digits: 4, Secret: "JBSWY3DP", Counter: 3}, Type: "HOTP"},
+				{Issuer: "iss-3", Info: info{Digits: 0, Secret: "JBSWY3DP", Period: 20}, Type: "TOTP"},
+				{Issuer: "iss-4", Info: info{Digits: 4, Secret: "JBSWY3DP", Algo: "SHA256"}, Type: "TOTP"},
 				{Issuer: "iss-5"},
+				{Issuer: "iss-6", Info: info{Digits: 5, Secret: "JBSWY3DP", Period: 30}, Type: "steam"},
+				{Issuer: "iss-7", Info: info{Secret: "JBSWY3DP", Pin: "1234"}, Type: "motp"},
 			},
+			nil,
 			[]vaults.Entry{
-				{Issuer: "iss-1", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 30},
-				{Issuer: "iss-3", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 20},
-				{Issuer: "iss-4", Digits: 4, Secret: "secret", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-2", Digits: 4, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+				{Issuer: "iss-3", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 20},
+				{Issuer: "iss-4", Digits: 4, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-6", Digits: 5, Secret: "JBSWY3DP", Type: "STEAM", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-7", Digits: 6, Secret: "JBSWY3DP", Pin: "1234", Type: "MOTP", Algorithm: "MD5", Period: 10},
+			},
+		},
+		{
+			"maps groups to tags",
+			[]entry{
+				{Issuer: "iss-1", Info: info{Secret: "JBSWY3DP"}, Type: "TOTP", Groups: []string{"uuid-1", "uuid-2", "unknown"}},
+				{Issuer: "iss-2", Info: info{Secret: "JBSWY3DP"}, Type: "TOTP", Group: "legacy"},
+				{Issuer: "iss-3", Info: info{Secret: "JBSWY3DP"}, Type: "TOTP"},
+			},
+			[]group{{UUID: "uuid-1", Name: "work"}, {UUID: "uuid-2", Name: "mail"}},
+			[]vaults.Entry{
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30, Tags: []string{"work", "mail"}},
+				{Issuer: "iss-2", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30, Tags: []string{"legacy"}},
+				{Issuer: "iss-3", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			entries := (&aegis{db: db{Entries: tt.input}}).Entries()
+			entries := (&aegis{db: db{Entries: tt.input, Groups: tt.groups}}).Entries()
 			if !reflect.DeepEqual(entries, tt.want) {
 				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
 			}
 		})
 	}
 }
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		password string
+	}{
+		{"encrypted", "testdata/aegis-export-test.json", "andcli-test"},
+		{"plain", "testdata/aegis-export-plain.json", ""},
+	}
+
+	added := vaults.Entry{
+		Secret: "GEZDGNBVGY3TQOJQ",
+		Issuer: "GitHub",
+		Label:  "alice",
+		Type:   "TOTP",
+		Tags:   []string{"work"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig, err := os.ReadFile(tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filename := filepath.Join(t.TempDir(), "vault.json")
+			if err := os.WriteFile(filename, orig, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := Open(filename, []byte(tt.password))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := v.(vaults.Writable)
+			if err := w.Add(added); err != nil {
+				t.Fatal(err)
+			}
+
+			updated := v.Entries()[0]
+			updated.Label = "renamed"
+			if err := w.Update(0, updated); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.Update(2, updated); err == nil {
+				t.Fatal("Update() expected error for an invalid index, got none")
+			}
+
+			if err := w.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			backup, err := os.ReadFile(filename + ".bak")
+			if err != nil || !bytes.Equal(backup, orig) {
+				t.Fatalf("Save(): backup does not match the original file: %v", err)
+			}
+
+			v, err = Open(filename, []byte(tt.password))
+			if err != nil {
+				t.Fatalf("Save(): reopen: %v", err)
+			}
+
+			entries := v.Entries()
+			if len(entries) != 2 || entries[0].Label != "renamed" {
+				t.Fatalf("Save(): have %#v", entries)
+			}
+
+			if got := entries[1]; got.Issuer != "GitHub" || got.Label != "alice" || got.Secret != added.Secret || !reflect.DeepEqual(got.Tags, added.Tags) {
+				t.Fatalf("Save(): have %#v, want %#v", got, added)
+			}
+
+			if err := v.(vaults.Writable).Remove(0); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := v.(vaults.Writable).Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			if v, err = Open(filename, []byte(tt.password)); err != nil || len(v.Entries()) != 1 {
+				t.Fatalf("Save(): remove: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdate_info(t *testing.T) {
+	raw := []byte(`{"version":3,"entries":[{"type":"totp","uuid":"uuid-1","name":"alice","issuer":"GitHub",` +
+		`"info":{"secret":"GEZDGNBVGY3TQOJQ","algo":"SHA1","digits":6,"period":30,"pin":"1234","extra":"kept"}}]}`)
+
+	v := &aegis{}
+	if err := v.db.parse(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	e := v.Entries()[0]
+	e.Digits, e.Pin = 8, ""
+	if err := v.Update(0, e); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct{ Info map[string]any }
+	if err := json.Unmarshal(v.db.Entries[0].raw, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"secret": "GEZDGNBVGY3TQOJQ", "algo": "SHA1", "digits": 8.0, "period": 30.0, "counter": 0.0, "extra": "kept"}
+	if !reflect.DeepEqual(got.Info, want) {
+		t.Errorf("Update(): have info %v, want %v", got.Info, want)
+	}
+}
+
+func TestAdd_groups(t *testing.T) {
+	v := &aegis{db: db{Version: 3, Groups: []group{{UUID: "uuid-1", Name: "work"}}}}
+
+	e := vaults.Entry{Secret: "JBSWY3DP", Issuer: "iss-1", Type: "TOTP", Tags: []string{"Work", "mail"}}
+	if err := v.Add(e); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.db.Groups) != 2 || v.db.Entries[0].Groups[0] != "uuid-1" {
+		t.Fatalf("Add(): have groups %#v", v.db.Groups)
+	}
+
+	if got := v.Entries()[0].Tags; !reflect.DeepEqual(got, []string{"work", "mail"}) {
+		t.Fatalf("Add(): have tags %v", got)
+	}
+
+	if err := v.Add(vaults.Entry{Issuer: "iss-2", Type: "TOTP"}); err == nil {
+		t.Fatal("Add() expected error for a missing secret, got none")
+	}
+}
+
+func TestAdd_invalidSecret(t *testing.T) {
+	v := &aegis{db: db{Version: 3}}
+	for _, secret := range []string{"not-base32!", "JBSWY3", "===="} {
+		e := vaults.Entry{Secret: secret, Issuer: "iss-1", Type: "TOTP"}
+		if err := v.Add(e); !errors.Is(err, vaults.ErrInvalidSecret) {
+			t.Errorf("Add(%q) error = %v, want %v", secret, err, vaults.ErrInvalidSecret)
+		}
+	}
+
+	if len(v.Entries()) != 0 {
+		t.Errorf("Add(): have entries %v", v.Entries())
+	}
+}
+
+func TestAdd_motpHex(t *testing.T) {
+	v := &aegis{db: db{Version: 3}}
+
+	e := vaults.Entry{Secret: "3132333435363738", Pin: "1234", Issuer: "iss-1", Type: "MOTP", HexSecret: true}
+	if err := v.Add(e); err != nil {
+		t.Fatal(err)
+	}
+
+	got := v.Entries()[0]
+	if got.Secret != "GEZDGNBVGY3TQ" || got.HexSecret {
+		t.Fatalf("Add(): have secret %q, hex %v", got.Secret, got.HexSecret)
+	}
+
+	// the hashed secret is unchanged.
+	if uri := got.URI(); !strings.Contains(uri, "secret=3132333435363738") {
+		t.Errorf("Add(): have uri %q", uri)
+	}
+
+	e.Secret = "12345"
+	if err := v.Add(e); !errors.Is(err, vaults.ErrInvalidSecret) {
+		t.Errorf("Add(%q) error = %v, want %v", e.Secret, err, vaults.ErrInvalidSecret)
+	}
+}
+
+func TestExport(t *testing.T) {
+	entries := []vaults.Entry{
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "GitHub", Label: "alice", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30, Tags: []string{"work", "dev"}},
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "Counter", Type: "HOTP", Algorithm: "SHA256", Digits: 8, Counter: 3},
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "Steam", Type: "STEAM", Algorithm: "SHA1", Digits: 5, Period: 30},
+	}
+
+	tests := []struct {
+		name      string
+		password  string
+		encrypted bool
+	}{
+		{"encrypted", "andcli-test", true},
+		{"plain", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Export(entries, []byte(tt.password))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filename := filepath.Join(t.TempDir(), "export.json")
+			if err := os.WriteFile(filename, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if encrypted, _ := IsEncrypted(filename); encrypted != tt.encrypted {
+				t.Fatalf("Export(): encrypted = %v, want %v", encrypted, tt.encrypted)
+			}
+
+			v, err := Open(filename, []byte(tt.password))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := v.Entries(); !reflect.DeepEqual(got, entries) {
+				t.Fatalf("Export(): want %#v\nhave %#v", entries, got)
+			}
+		})
+	}
+
+	_, err := Export([]vaults.Entry{{Issuer: "invalid", Secret: "not-base32!", Type: "TOTP"}}, nil)
+	if want := "invalid: aegis: " + vaults.ErrInvalidSecret.Error(); err == nil || err.Error() != want {
+		t.Fatalf("Export() error = %v, want %s", err, want)
+	}
+}
@@ -2,6 +2,7 @@ package andotp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -14,6 +15,8 @@ const vaultType = vaults.ANDOTP
 
 var _ vaults.Vault = &andotp{}
 
+var errInvalidFile = errors.New("not an andOTP export: want a list of entries with secret and type")
+
 type (
 	andotp struct{ entries []entry }
 
@@ -26,6 +29,7 @@ type (
 		Algorithm string
 		Thumbnail string
 		Period    int
+		Counter   int
 		LastUsed  int64 `json:"last_used"`
 		UsedFreq  int   `json:"used_frequency"`
 		Tags      []string
@@ -38,8 +42,14 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	b, err = gao.Decrypt(b, string(pass))
-	if err != nil {
+	if encrypted(b) {
+		b, err = gao.Decrypt(b, string(pass))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", vaultType, err)
+		}
+	}
+
+	if err := validate(b); err != nil {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
@@ -51,6 +61,24 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 	return &andotp{entries}, nil
 }
 
+// IsEncrypted reports whether the given file is an encrypted export.
+func IsEncrypted(filename string) (bool, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	if encrypted(b) {
+		return true, nil
+	}
+
+	if err := validate(b); err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return false, nil
+}
+
 func (v andotp) Entries() []vaults.Entry {
 	entries := make([]vaults.Entry, 0)
 
@@ -64,6 +92,7 @@ func (v andotp) Entries() []vaults.Entry {
 			Tags:      e.Tags,
 			Digits:    e.Digits,
 			Period:    e.Period,
+			Counter:   e.Counter,
 		}
 
 		if err := entry.SanitizeAndValidate(); err == nil {
@@ -73,3 +102,27 @@ func (v andotp) Entries() []vaults.Entry {
 
 	return entries
 }
+
+// Plain exports are a JSON list, encrypted exports are binary.
+func encrypted(b []byte) bool {
+	return !json.Valid(b)
+}
+
+// Checks that the plain export is a list of objects with a secret and a
+// type, so that any other JSON file is not opened as empty vault.
+func validate(b []byte) error {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return errInvalidFile
+	}
+
+	for _, item := range items {
+		_, secret := item["secret"]
+		_, typ := item["type"]
+		if !secret || !typ {
+			return errInvalidFile
+		}
+	}
+
+	return nil
+}
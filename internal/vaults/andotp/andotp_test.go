@@ -15,7 +15,10 @@ func TestOpen(t *testing.T) {
 		fails    bool
 	}{
 		{"decrypts", "testdata/andotp_test.json.aes", "andcli-test", false},
+		{"opens plain", "testdata/andotp_test.json", "", false},
 		{"fails: wrong password", "testdata/andotp_test.json.aes", "invalid", true},
+		{"fails: other json list", "testdata/andotp_other.json", "", true},
+		{"fails: json object", "testdata/andotp_object.json", "", true},
 	}
 
 	for _, tt := range tests {
@@ -40,6 +43,33 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/andotp_test.json.aes", true, false},
+		{"plain", "testdata/andotp_test.json", false, false},
+		{"fails: missing file", "testdata/nosuchfile.json", false, true},
+		{"fails: other json list", "testdata/andotp_other.json", false, true},
+		{"fails: json object", "testdata/andotp_object.json", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestEntries(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -49,16 +79,17 @@ func TestEntries(t *testing.T) {
 		{
 			"mitigates missing fields",
 			[]entry{
-				{Issuer: "iss-1", Digits: 6, Secret: "secret", Type: "TOTP"},
-				{Issuer: "iss-2", Digits: 4, Secret: "secret", Type: "HOTP"},
-				{Issuer: "iss-3", Digits: 0, Secret: "secret", Type: "TOTP", Period: 20},
-				{Issuer: "iss-4", Digits: 4, Secret: "secret", Type: "TOTP", Algorithm: "SHA256"},
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP"},
+				{Issuer: "iss-2", Digits: 4, Secret: "JBSWY3DP", Type: "HOTP", Counter: 3},
+				{Issuer: "iss-3", Digits: 0, Secret: "JBSWY3DP", Type: "TOTP", Period: 20},
+				{Issuer: "iss-4", Digits: 4, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256"},
 				{Issuer: "iss-5"},
 			},
 			[]vaults.Entry{
-				{Issuer: "iss-1", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 30},
-				{Issuer: "iss-3", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 20},
-				{Issuer: "iss-4", Digits: 4, Secret: "secret", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-2", Digits: 4, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+				{Issuer: "iss-3", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 20},
+				{Issuer: "iss-4", Digits: 4, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256", Period: 30},
 			},
 		},
 	}
@@ -0,0 +1,289 @@
+package bitwarden
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const vaultType = vaults.BITWARDEN
+
+var _ vaults.Vault = &bitwarden{}
+
+// supported key derivation functions.
+const (
+	kdfPBKDF2 = iota
+	kdfArgon2id
+)
+
+// upper bounds of the Argon2id parameters, as enforced by Bitwarden. Memory
+// is given in MiB.
+const (
+	maxArgon2Memory      = 1024
+	maxArgon2Parallelism = 16
+)
+
+var (
+	errAccountRestricted = errors.New("account restricted exports are not supported, export with a password instead")
+	errInvalidPassword   = errors.New("invalid password")
+	errEncString         = errors.New("unsupported encrypted string")
+	errKDFParameters     = errors.New("invalid key derivation parameters")
+	errInvalidFile       = errors.New("not a Bitwarden export: missing encrypted and items or data")
+)
+
+type (
+	// bitwarden only implements the essentials for reading OTP data.
+	bitwarden struct {
+		Encrypted         bool
+		PasswordProtected bool
+		Salt              string
+		KdfType           int
+		KdfIterations     int
+		KdfMemory         int
+		KdfParallelism    int
+		EncKeyValidation  string `json:"encKeyValidation_DO_NOT_EDIT"`
+		Data              string
+		Items             []item
+	}
+
+	item struct {
+		Type  int
+		Name  string
+		Login *struct{ Username, Totp string }
+	}
+)
+
+func Open(filename string, pass []byte) (vaults.Vault, error) {
+	v, err := read(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	if !v.Encrypted {
+		return v, nil
+	}
+
+	if !v.PasswordProtected {
+		return nil, fmt.Errorf("%s: %w", vaultType, errAccountRestricted)
+	}
+
+	encKey, macKey, err := v.keys(pass)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	if _, err := decrypt(v.EncKeyValidation, encKey, macKey); err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	b, err := decrypt(v.Data, encKey, macKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return v, nil
+}
+
+// IsEncrypted reports whether the given file is an encrypted export.
+func IsEncrypted(filename string) (bool, error) {
+	v, err := read(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return v.Encrypted, nil
+}
+
+func (v bitwarden) Entries() []vaults.Entry {
+	entries := make([]vaults.Entry, 0)
+
+	for _, i := range v.Items {
+		if i.Login == nil || i.Login.Totp == "" {
+			continue
+		}
+
+		entry, err := parseTOTP(i.Login.Totp)
+		if err != nil {
+			log.Printf("%q: %s", i.Name, err)
+			continue
+		}
+
+		entry.Issuer = i.Name
+		entry.Label = i.Login.Username
+
+		if err := entry.SanitizeAndValidate(); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Derives the encryption and MAC keys from the password. The master key is
+// stretched with HKDF, like Bitwarden does for password protected exports.
+func (v bitwarden) keys(pass []byte) ([]byte, []byte, error) {
+	var key []byte
+
+	if err := v.validateKDF(); err != nil {
+		return nil, nil, err
+	}
+
+	switch v.KdfType {
+	case kdfPBKDF2:
+		key = pbkdf2.Key(pass, []byte(v.Salt), v.KdfIterations, 32, sha256.New)
+	case kdfArgon2id:
+		salt := sha256.Sum256([]byte(v.Salt))
+		key = argon2.IDKey(pass, salt[:], uint32(v.KdfIterations), uint32(v.KdfMemory)*1024, uint8(v.KdfParallelism), 32)
+	default:
+		return nil, nil, fmt.Errorf("unsupported kdf type %d", v.KdfType)
+	}
+
+	encKey, macKey := make([]byte, 32), make([]byte, 32)
+
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("enc")), encKey); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := io.ReadFull(hkdf.Expand(sha256.New, key, []byte("mac")), macKey); err != nil {
+		return nil, nil, err
+	}
+
+	return encKey, macKey, nil
+}
+
+// Checks the key derivation parameters of the file, as argon2 panics on
+// zero values and huge ones exhaust the memory.
+func (v bitwarden) validateKDF() error {
+	if v.KdfIterations < 1 {
+		return errKDFParameters
+	}
+
+	if v.KdfType == kdfArgon2id {
+		if v.KdfMemory < 1 || v.KdfMemory > maxArgon2Memory ||
+			v.KdfParallelism < 1 || v.KdfParallelism > maxArgon2Parallelism {
+			return errKDFParameters
+		}
+	}
+
+	return nil
+}
+
+// Decrypts an encrypted string of type 2 ("2.iv|data|mac"):
+// AES-256-CBC with a HMAC-SHA256 over iv and data.
+func decrypt(s string, encKey, macKey []byte) ([]byte, error) {
+	typ, rest, ok := strings.Cut(s, ".")
+	if !ok || typ != "2" {
+		return nil, errEncString
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) != 3 {
+		return nil, errEncString
+	}
+
+	var iv, data, sum []byte
+	for i, dst := range []*[]byte{&iv, &data, &sum} {
+		b, err := base64.StdEncoding.DecodeString(parts[i])
+		if err != nil {
+			return nil, err
+		}
+		*dst = b
+	}
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return nil, errInvalidPassword
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errEncString
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	return unpad(plain)
+}
+
+// Parses the totp value of a login item, which is either an otpauth URI,
+// a steam:// URI or a plain base32 secret. Plain secrets are often stored
+// in lowercase or split by spaces.
+func parseTOTP(s string) (vaults.Entry, error) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case strings.HasPrefix(s, "otpauth://"):
+		return vaults.ParseURI(s)
+	case strings.HasPrefix(strings.ToLower(s), "steam://"):
+		return vaults.Entry{Secret: vaults.NormalizeSecret(s[len("steam://"):]), Type: vaults.STEAM}, nil
+	default:
+		return vaults.Entry{Secret: vaults.NormalizeSecret(s), Type: vaults.TOTP}, nil
+	}
+}
+
+// removes PKCS#7 padding.
+func unpad(b []byte) ([]byte, error) {
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, errInvalidPassword
+	}
+
+	if !bytes.Equal(b[len(b)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, errInvalidPassword
+	}
+
+	return b[:len(b)-n], nil
+}
+
+// reads and parses the export file.
+func read(filename string) (*bitwarden, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	v := new(bitwarden)
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, err
+	}
+
+	// any other JSON object would be opened as empty vault.
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, err
+	}
+
+	_, encrypted := keys["encrypted"]
+	_, items := keys["items"]
+	_, data := keys["data"]
+	if !encrypted || (!items && !data) {
+		return nil, errInvalidFile
+	}
+
+	return v, nil
+}
@@ -0,0 +1,163 @@
+package bitwarden
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		password string
+		err      error
+		fails    bool
+	}{
+		{"decrypts pbkdf2", "testdata/bitwarden-export-pbkdf2.json", "andcli-test", nil, false},
+		{"decrypts argon2id", "testdata/bitwarden-export-argon2.json", "andcli-test", nil, false},
+		{"opens plain", "testdata/bitwarden-export-plain.json", "", nil, false},
+		{"fails: wrong password", "testdata/bitwarden-export-pbkdf2.json", "invalid", errInvalidPassword, true},
+		{"fails: account restricted", "testdata/bitwarden-export-account.json", "andcli-test", errAccountRestricted, true},
+		{"fails: other JSON", "testdata/bitwarden-other.json", "", errInvalidFile, true},
+		{"fails: no items", "testdata/bitwarden-no-items.json", "", errInvalidFile, true},
+		{"fails: missing file", "testdata/nosuchfile.json", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Open(tt.filename, []byte(tt.password))
+			if tt.fails {
+				if err == nil {
+					t.Fatal("Open() expected error, got none")
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Fatalf("Open() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries := v.Entries()
+			if len(entries) != 3 {
+				t.Fatalf("Open() expected len to be 3, have %v", len(entries))
+			}
+
+			for i, want := range []string{"demo1", "demo2", "demo3"} {
+				if entries[i].Label != want {
+					t.Fatalf("Open() have %v, want %s", entries[i].Label, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/bitwarden-export-pbkdf2.json", true, false},
+		{"plain", "testdata/bitwarden-export-plain.json", false, false},
+		{"fails: other JSON", "testdata/bitwarden-other.json", false, true},
+		{"fails: missing file", "testdata/nosuchfile.json", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBitwarden_keys(t *testing.T) {
+	tests := []struct {
+		name  string
+		v     bitwarden
+		fails bool
+	}{
+		{"pbkdf2", bitwarden{KdfType: kdfPBKDF2, KdfIterations: 1}, false},
+		{"argon2id", bitwarden{KdfType: kdfArgon2id, KdfIterations: 1, KdfMemory: 1, KdfParallelism: 1}, false},
+		{"fails: pbkdf2 without iterations", bitwarden{KdfType: kdfPBKDF2}, true},
+		{"fails: argon2id without iterations", bitwarden{KdfType: kdfArgon2id, KdfMemory: 64, KdfParallelism: 4}, true},
+		{"fails: argon2id without memory", bitwarden{KdfType: kdfArgon2id, KdfIterations: 3, KdfParallelism: 4}, true},
+		{"fails: argon2id memory too large", bitwarden{KdfType: kdfArgon2id, KdfIterations: 3, KdfMemory: 1 << 30, KdfParallelism: 4}, true},
+		{"fails: argon2id without parallelism", bitwarden{KdfType: kdfArgon2id, KdfIterations: 3, KdfMemory: 64}, true},
+		{"fails: argon2id parallelism too large", bitwarden{KdfType: kdfArgon2id, KdfIterations: 3, KdfMemory: 64, KdfParallelism: 256}, true},
+		{"fails: unknown kdf", bitwarden{KdfType: 2, KdfIterations: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encKey, macKey, err := tt.v.keys([]byte("andcli-test"))
+			if (err != nil) != tt.fails {
+				t.Fatalf("keys() error = %v, wantErr %v", err, tt.fails)
+			}
+			if !tt.fails && (len(encKey) != 32 || len(macKey) != 32) {
+				t.Errorf("keys() have key lengths %d, %d, want 32", len(encKey), len(macKey))
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	login := func(username, totp string) *struct{ Username, Totp string } {
+		return &struct{ Username, Totp string }{username, totp}
+	}
+
+	tests := []struct {
+		name  string
+		input []item
+		want  []vaults.Entry
+	}{
+		{
+			"maps totp values",
+			[]item{
+				{Type: 1, Name: "iss-1", Login: login("demo1", "otpauth://totp/other:demo?secret=JBSWY3DP&digits=8")},
+				{Type: 1, Name: "iss-2", Login: login("demo2", " JBSWY3DP ")},
+				{Type: 1, Name: "iss-3", Login: login("demo3", "steam://JBSWY3DP")},
+				{Type: 1, Name: "iss-4", Login: login("demo4", "")},
+				{Type: 1, Name: "iss-5", Login: login("demo5", "otpauth://%zz")},
+				{Type: 1, Name: "iss-6", Login: login("demo6", "jbsw y3dp ehpk 3pxp")},
+				{Type: 1, Name: "iss-7", Login: login("demo7", "steam://jbsw y3dp")},
+				{Type: 1, Name: "iss-8", Login: login("demo8", "not base32!")},
+				{Type: 2, Name: "note"},
+			},
+			[]vaults.Entry{
+				{Issuer: "iss-1", Label: "demo1", Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Digits: 8, Period: 30},
+				{Issuer: "iss-2", Label: "demo2", Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Issuer: "iss-3", Label: "demo3", Secret: "JBSWY3DP", Type: "STEAM", Algorithm: "SHA1", Digits: 5, Period: 30},
+				{Issuer: "iss-6", Label: "demo6", Secret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Issuer: "iss-7", Label: "demo7", Secret: "JBSWY3DP", Type: "STEAM", Algorithm: "SHA1", Digits: 5, Period: 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := bitwarden{Items: tt.input}.Entries()
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
+			}
+
+			for _, e := range entries {
+				if token, _ := e.Generate(); token == "" {
+					t.Errorf("Entries(): %s: no token", e.Issuer)
+				}
+			}
+		})
+	}
+}
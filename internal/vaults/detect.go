@@ -0,0 +1,180 @@
+package vaults
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+var ErrUnknownType = errors.New("unable to detect vault type")
+
+var (
+	stratumHeader       = []byte("AUTHENTICATORPRO")
+	stratumLegacyHeader = []byte("AuthenticatorPro")
+	kdbxSignature       = []byte{0x03, 0xd9, 0xa2, 0x9a, 0x67, 0xfb, 0x4b, 0xb5}
+	zipSignature        = []byte{0x50, 0x4b, 0x03, 0x04}
+	pgpArmorHeader      = []byte("-----BEGIN PGP MESSAGE-----")
+	ageHeader           = []byte("age-encryption.org/")
+	ageArmorHeader      = []byte("-----BEGIN AGE ENCRYPTED FILE-----")
+	javaMagic           = []byte{0xac, 0xed, 0x00, 0x05}
+)
+
+// Detect guesses the vault type of a file by looking at its content.
+func Detect(filename string) (Type, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	if t := detect(b); t != "" {
+		return t, nil
+	}
+
+	return "", ErrUnknownType
+}
+
+// Returns the detected type or an empty string.
+func detect(b []byte) Type {
+	trimmed := bytes.TrimSpace(b)
+
+	switch {
+	case bytes.HasPrefix(b, stratumHeader), bytes.HasPrefix(b, stratumLegacyHeader):
+		return STRATUM
+	case bytes.HasPrefix(b, kdbxSignature):
+		return KEEPASS
+	case bytes.HasPrefix(b, javaMagic):
+		return FREEOTP
+	case bytes.HasPrefix(b, zipSignature), bytes.HasPrefix(trimmed, pgpArmorHeader):
+		return PROTON
+	case bytes.HasPrefix(b, ageHeader), bytes.HasPrefix(trimmed, ageArmorHeader):
+		return OTPAUTH
+	case json.Valid(trimmed):
+		return detectJSON(trimmed)
+	case isURIList(trimmed, "otpauth://"):
+		return OTPAUTH
+	case isURIList(trimmed, "otpauth-migration://"):
+		return GOOGLE
+	case IsPGP(b):
+		// binary PGP messages. Proton exports are always armored.
+		return OTPAUTH
+	case isAndOTP(b):
+		return ANDOTP
+	}
+
+	return ""
+}
+
+// Detects the type of plain or partially encrypted JSON exports.
+func detectJSON(b []byte) Type {
+	// andotp plain exports are the only ones using a top level list.
+	if b[0] == '[' {
+		return ANDOTP
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return ""
+	}
+
+	has := func(k string) bool { _, ok := keys[k]; return ok }
+
+	switch {
+	case has("header") && has("db"):
+		return AEGIS
+	case has("servicesEncrypted"), has("services") && has("schemaVersion"):
+		return TWOFAS
+	case has("Authenticators"):
+		return STRATUM
+	case has("kdfParams") && has("encryptedData"):
+		return ENTE
+	case has("tokenOrder") && has("tokens"):
+		return FREEOTPPLUS
+	case has("passwordProtected"), has("encrypted") && has("items"):
+		return BITWARDEN
+	case has("vaults"):
+		return PROTON
+	}
+
+	return ""
+}
+
+// Returns true if the first line which is not a comment starts with prefix.
+func isURIList(b []byte, prefix string) bool {
+	for line := range bytes.Lines(b) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		return bytes.HasPrefix(line, []byte(prefix))
+	}
+	return false
+}
+
+// IsPGP returns true if b starts with an OpenPGP packet (RFC 9580) that
+// encrypts the session key of a message, with a password (SKESK, tag 3)
+// or a public key (PKESK, tag 1). Both have a known length and version.
+func IsPGP(b []byte) bool {
+	if len(b) < 2 || b[0]&0x80 == 0 {
+		return false
+	}
+
+	var tag byte
+	var length, offset uint64
+
+	if b[0]&0x40 != 0 {
+		// new format: the tag in the lower 6 bits, then 1, 2 or 5 length
+		// octets. Partial lengths are not allowed for these packets.
+		tag = b[0] & 0x3f
+		switch l := b[1]; {
+		case l < 192:
+			length, offset = uint64(l), 2
+		case l < 224 && len(b) >= 3:
+			length, offset = uint64(l-192)<<8+uint64(b[2])+192, 3
+		case l == 255 && len(b) >= 6:
+			length, offset = uint64(binary.BigEndian.Uint32(b[2:6])), 6
+		default:
+			return false
+		}
+	} else {
+		// old format: the tag in bits 2-5, the length type in bits 0-1.
+		tag = (b[0] >> 2) & 0x0f
+		switch lt := b[0] & 0x03; {
+		case lt == 0:
+			length, offset = uint64(b[1]), 2
+		case lt == 1 && len(b) >= 3:
+			length, offset = uint64(binary.BigEndian.Uint16(b[1:3])), 3
+		case lt == 2 && len(b) >= 5:
+			length, offset = uint64(binary.BigEndian.Uint32(b[1:5])), 5
+		default:
+			return false
+		}
+	}
+
+	if length < 2 || offset+length > uint64(len(b)) {
+		return false
+	}
+
+	switch version := b[offset]; tag {
+	case 1:
+		return version == 3 || version == 6
+	case 3:
+		return version >= 4 && version <= 6
+	}
+
+	return false
+}
+
+// Encrypted andotp backups have no header. They start with the PBKDF2
+// iteration count (4 bytes), followed by the salt (12), IV (12), the
+// payload and the auth tag (16). A sane iteration count is the best
+// indicator available.
+func isAndOTP(b []byte) bool {
+	if len(b) < 4+12+12+16 {
+		return false
+	}
+
+	n := binary.BigEndian.Uint32(b[:4])
+	return n >= 1000 && n <= 10_000_000
+}
@@ -0,0 +1,84 @@
+package vaults_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestDetect(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// binary files with the high bit set in the first byte, but no PGP
+	// packets, and an old format PGP packet.
+	binary := map[string][]byte{
+		"png":    append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...),
+		"jpeg":   append([]byte{0xff, 0xd8, 0xff, 0xe0}, make([]byte, 64)...),
+		"bom":    []byte("\xef\xbb\xbfotpauth list without scheme\n"),
+		"random": {0xc3, 0xff, 0xff, 0xff, 0xff, 0xff, 0x04},
+		"pgp":    {0x8c, 0x0d, 0x04, 0x09, 0x03, 0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x60, 0xd2, 0x00},
+	}
+
+	files := make(map[string]string)
+	for name, b := range binary {
+		files[name] = filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(files[name], b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		filename string
+		want     vaults.Type
+		err      error
+	}{
+		{"andotp/testdata/andotp_test.json.aes", vaults.ANDOTP, nil},
+		{"andotp/testdata/andotp_test.json", vaults.ANDOTP, nil},
+		{"aegis/testdata/aegis-export-test.json", vaults.AEGIS, nil},
+		{"aegis/testdata/aegis-export-plain.json", vaults.AEGIS, nil},
+		{"twofas/testdata/twofas-export-test.2fas", vaults.TWOFAS, nil},
+		{"twofas/testdata/twofas-export-plain.2fas", vaults.TWOFAS, nil},
+		{"stratum/testdata/backup-andcli-test.stratum", vaults.STRATUM, nil},
+		{"stratum/testdata/backup-legacy-andcli-test.stratum", vaults.STRATUM, nil},
+		{"stratum/testdata/backup-andcli-test.json", vaults.STRATUM, nil},
+		{"keepass/testdata/keepass-test.kdbx", vaults.KEEPASS, nil},
+		{"protonpass/testdata/protonpass-test.pgp", vaults.PROTON, nil},
+		{"protonpass/testdata/protonpass-test.pgp.zip", vaults.PROTON, nil},
+		{"protonpass/testdata/protonpass-test.zip", vaults.PROTON, nil},
+		{"otpauth/testdata/otpauth-test.txt", vaults.OTPAUTH, nil},
+		{"otpauth/testdata/otpauth-test.txt.age", vaults.OTPAUTH, nil},
+		{"otpauth/testdata/otpauth-test.txt.gpg", vaults.OTPAUTH, nil},
+		{"googleauth/testdata/googleauth-test.txt", vaults.GOOGLE, nil},
+		{"bitwarden/testdata/bitwarden-export-pbkdf2.json", vaults.BITWARDEN, nil},
+		{"bitwarden/testdata/bitwarden-export-plain.json", vaults.BITWARDEN, nil},
+		{"bitwarden/testdata/bitwarden-export-account.json", vaults.BITWARDEN, nil},
+		{"ente/testdata/ente-export-test.json", vaults.ENTE, nil},
+		{"freeotp/testdata/freeotp-backup.xml", vaults.FREEOTP, nil},
+		{"freeotpplus/testdata/freeotpplus-test.json", vaults.FREEOTPPLUS, nil},
+		{"aegis/testdata/aegis-invalid-file.json", "", vaults.ErrUnknownType},
+		{empty, "", vaults.ErrUnknownType},
+		{files["png"], "", vaults.ErrUnknownType},
+		{files["jpeg"], "", vaults.ErrUnknownType},
+		{files["bom"], "", vaults.ErrUnknownType},
+		{files["random"], "", vaults.ErrUnknownType},
+		{files["pgp"], vaults.OTPAUTH, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.filename), func(t *testing.T) {
+			got, err := vaults.Detect(tt.filename)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Detect() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package ente
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+	"golang.org/x/crypto/argon2"
+)
+
+const vaultType = vaults.ENTE
+
+var _ vaults.Vault = &ente{}
+
+var errKDFParameters = errors.New("invalid key derivation parameters")
+
+type (
+	ente struct {
+		Version   int
+		KdfParams struct {
+			MemLimit, OpsLimit int
+			Salt               string
+		}
+		EncryptedData   string
+		EncryptionNonce string
+		//
+		uris []string
+	}
+
+	// display settings, stored as JSON in the codeDisplay URI parameter.
+	codeDisplay struct {
+		Trashed bool
+		Tags    []string
+	}
+)
+
+func Open(filename string, pass []byte) (vaults.Vault, error) {
+	v, err := read(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	b, err := v.decrypt(pass)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	for line := range strings.SplitSeq(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			v.uris = append(v.uris, line)
+		}
+	}
+
+	return v, nil
+}
+
+// IsEncrypted always returns true for a readable file: plain Ente exports
+// are URI lists and handled by the otpauth vault type.
+func IsEncrypted(filename string) (bool, error) {
+	if _, err := read(filename); err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return true, nil
+}
+
+func (v ente) Entries() []vaults.Entry {
+	entries := make([]vaults.Entry, 0)
+
+	for i, s := range v.uris {
+		entry, err := vaults.ParseURI(s)
+		if err != nil {
+			log.Printf("entry %d: %s", i+1, err)
+			continue
+		}
+
+		display := parseCodeDisplay(s)
+		if display.Trashed {
+			continue
+		}
+		entry.Tags = display.Tags
+
+		if err := entry.SanitizeAndValidate(); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Derives the key with Argon2id, using the libsodium defaults for anything
+// but the given limits, and decrypts the export data.
+func (v ente) decrypt(pass []byte) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(v.KdfParams.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := base64.StdEncoding.DecodeString(v.EncryptionNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(v.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	// the limits come from the file, and argon2 panics on zero iterations.
+	p := v.KdfParams
+	if p.OpsLimit < 1 || p.MemLimit <= 0 {
+		return nil, errKDFParameters
+	}
+
+	key := argon2.IDKey(pass, salt, uint32(p.OpsLimit), uint32(p.MemLimit/1024), 1, 32)
+
+	return decryptStream(key, header, data)
+}
+
+// Returns the display settings of an URI, if any.
+func parseCodeDisplay(s string) codeDisplay {
+	var d codeDisplay
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return d
+	}
+
+	if v := u.Query().Get("codeDisplay"); v != "" {
+		if err := json.Unmarshal([]byte(v), &d); err != nil {
+			log.Printf("codeDisplay: %s", err)
+		}
+	}
+
+	return d
+}
+
+// reads and parses the export file.
+func read(filename string) (*ente, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	v := new(ente)
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,106 @@
+package ente
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		password string
+		fails    bool
+	}{
+		{"decrypts", "testdata/ente-export-test.json", "andcli-test", false},
+		{"fails: wrong password", "testdata/ente-export-test.json", "invalid", true},
+		{"fails: invalid file", "../otpauth/testdata/otpauth-test.txt", "andcli-test", true},
+		{"fails: missing ops limit", "testdata/ente-export-no-ops.json", "andcli-test", true},
+		{"fails: missing memory limit", "testdata/ente-export-no-mem.json", "andcli-test", true},
+		{"fails: missing file", "testdata/nosuchfile.json", "andcli-test", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Open(tt.filename, []byte(tt.password))
+			if tt.fails {
+				if err == nil {
+					t.Fatal("Open() expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries := v.Entries()
+			if len(entries) != 3 {
+				t.Fatalf("Open() expected len to be 3, have %v", len(entries))
+			}
+
+			for i, want := range []string{"demo1", "demo2", "demo3"} {
+				if entries[i].Label != want {
+					t.Fatalf("Open() have %v, want %s", entries[i].Label, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/ente-export-test.json", true, false},
+		{"fails: invalid file", "../otpauth/testdata/otpauth-test.txt", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []vaults.Entry
+	}{
+		{
+			"skips trashed, reads tags",
+			[]string{
+				`otpauth://totp/iss-1:demo1?secret=JBSWY3DP&codeDisplay={"pinned":true,"trashed":false,"tags":["work","mail"]}`,
+				`otpauth://totp/iss-2:demo2?secret=JBSWY3DP&codeDisplay={"trashed":true}`,
+				`otpauth://hotp/iss-3:demo3?secret=JBSWY3DP&counter=3&codeDisplay=invalid`,
+				`invalid://totp/iss-4:demo4?secret=JBSWY3DP`,
+			},
+			[]vaults.Entry{
+				{Issuer: "iss-1", Label: "demo1", Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30, Tags: []string{"work", "mail"}},
+				{Issuer: "iss-3", Label: "demo3", Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Digits: 6, Counter: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := ente{uris: tt.input}.Entries()
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
+			}
+		})
+	}
+}
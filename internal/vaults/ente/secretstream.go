@@ -0,0 +1,98 @@
+package ente
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/poly1305"
+)
+
+const (
+	streamHeaderBytes = 24
+	streamABytes      = 1 + poly1305.TagSize
+)
+
+var errAuthFailed = errors.New("message authentication failed")
+
+// Decrypts a stream consisting of a single message, encrypted with libsodium's
+// crypto_secretstream_xchacha20poly1305 without additional data. The header
+// holds the nonce, the message starts with the encrypted tag byte, followed
+// by the ciphertext and the MAC.
+func decryptStream(key, header, c []byte) ([]byte, error) {
+	if len(header) != streamHeaderBytes {
+		return nil, errors.New("invalid stream header")
+	}
+
+	if len(c) < streamABytes {
+		return nil, errors.New("stream too short")
+	}
+
+	subkey, err := chacha20.HChaCha20(key, header[:16])
+	if err != nil {
+		return nil, err
+	}
+
+	// the state nonce is the little endian counter (starting at 1),
+	// followed by the last 8 bytes of the header.
+	nonce := make([]byte, chacha20.NonceSize)
+	binary.LittleEndian.PutUint32(nonce, 1)
+	copy(nonce[4:], header[16:])
+
+	polyKey, err := keystream(subkey, nonce, 0, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := keystream(subkey, nonce, 1, 64)
+	if err != nil {
+		return nil, err
+	}
+	block[0] = c[0]
+
+	mlen := len(c) - streamABytes
+	ct, sum := c[1:1+mlen], c[1+mlen:]
+
+	var k [32]byte
+	copy(k[:], polyKey)
+
+	lens := make([]byte, 16)
+	binary.LittleEndian.PutUint64(lens[8:], uint64(len(block)+mlen))
+
+	mac := poly1305.New(&k)
+	mac.Write(block)
+	mac.Write(ct)
+	// libsodium pads with (16 - 64 + mlen) & 15 bytes.
+	mac.Write(make([]byte, (0x10-len(block)+mlen)&0xf))
+	mac.Write(lens)
+
+	if subtle.ConstantTimeCompare(mac.Sum(nil), sum) != 1 {
+		return nil, errAuthFailed
+	}
+
+	s, err := chacha20.NewUnauthenticatedCipher(subkey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	s.SetCounter(2)
+
+	plain := make([]byte, mlen)
+	s.XORKeyStream(plain, ct)
+
+	return plain, nil
+}
+
+// Returns n bytes of the ChaCha20 keystream, starting at the given block.
+func keystream(key, nonce []byte, counter uint32, n int) ([]byte, error) {
+	s, err := chacha20.NewUnauthenticatedCipher(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	s.SetCounter(counter)
+
+	b := make([]byte, n)
+	s.XORKeyStream(b, b)
+
+	return b, nil
+}
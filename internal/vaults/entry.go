@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 
 	"github.com/sahilm/fuzzy"
@@ -16,6 +17,7 @@ import (
 // Entry represents a generic vault entry.
 type Entry struct {
 	Secret    string
+	Pin       string
 	Issuer    string
 	Label     string
 	Type      string
@@ -23,19 +25,71 @@ type Entry struct {
 	Tags      []string
 	Digits    int
 	Period    int
+	Counter   int
+	Vault     string // name of the originating vault, if set
+	HexSecret bool   // mOTP secret is a hex string instead of base32
 }
 
+// Supported entry types.
+const (
+	TOTP   = "TOTP"
+	HOTP   = "HOTP"
+	STEAM  = "STEAM"
+	YANDEX = "YANDEX"
+	MOTP   = "MOTP"
+)
+
 var (
 	ErrMissingSecret = errors.New("missing secret value")
-	ErrInvalidType   = errors.New("entry is not a TOTP")
+	ErrInvalidType   = errors.New("entry type is not supported")
+	ErrMissingPin    = errors.New("missing pin value")
+	ErrInvalidSecret = errors.New("invalid secret value")
 )
 
+// Returns a generated OTP for the current entry, based on its type.
+// The second value is the expiration time for time based entries and 0
+// for counter based entries.
+func (e Entry) Generate() (string, int64) {
+	switch strings.ToUpper(e.Type) {
+	case HOTP:
+		return e.GenerateHOTP(), 0
+	case STEAM:
+		return e.GenerateSteam()
+	case YANDEX:
+		return e.GenerateYandex()
+	case MOTP:
+		return e.GenerateMOTP()
+	default:
+		return e.GenerateTOTP()
+	}
+}
+
 // Returns a generated OTP and expiration time for the current entry.
 func (e Entry) GenerateTOTP() (string, int64) {
 	totp := gotp.NewTOTP(e.Secret, e.Digits, e.Period, e.hasher())
 	return totp.NowWithExpiration()
 }
 
+// Returns a generated OTP for the current entry counter.
+func (e Entry) GenerateHOTP() string {
+	hotp := gotp.NewHOTP(e.Secret, e.Digits, e.hasher())
+	return hotp.At(e.Counter)
+}
+
+// IsCounterBased returns true if the entry is a HOTP.
+func (e Entry) IsCounterBased() bool {
+	return strings.ToUpper(e.Type) == HOTP
+}
+
+// Returns true if the entry type needs a PIN to generate tokens.
+func (e Entry) requiresPin() bool {
+	switch strings.ToUpper(e.Type) {
+	case YANDEX, MOTP:
+		return true
+	}
+	return false
+}
+
 // Returns a new gotp Hasher based on the entry algorithm.
 func (e Entry) hasher() *gotp.Hasher {
 	h := &gotp.Hasher{
@@ -89,10 +143,59 @@ func (e Entry) Description() string {
 }
 
 // Implementation of bubbletea listitem.FilterValue()
+// The title always comes first, followed by username, tags and vault.
 func (e Entry) FilterValue() string {
+	parts := []string{e.Title()}
+	if desc := e.Description(); desc != "" && desc != e.Title() {
+		parts = append(parts, desc)
+	}
+
+	parts = append(parts, e.Tags...)
+	if e.Vault != "" {
+		parts = append(parts, e.Vault)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// QualifiedName returns "title:username", or the title if there is no
+// username.
+func (e Entry) QualifiedName() string {
+	if desc := e.Description(); desc != "" && desc != e.Title() {
+		return fmt.Sprintf("%s:%s", e.Title(), desc)
+	}
 	return e.Title()
 }
 
+// HasTag returns true if the entry has the tag t or a tag below it, i.e.
+// "work" matches "Work" and "Work/Mail". Tags are compared case insensitive.
+func (e Entry) HasTag(t string) bool {
+	t = strings.ToLower(t)
+	for _, tag := range e.Tags {
+		tag = strings.ToLower(tag)
+		if tag == t || strings.HasPrefix(tag, t+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilter splits a search term into tag filters ("tag:work") and the
+// remaining search text.
+func ParseFilter(s string) (tags []string, term string) {
+	words := make([]string, 0)
+	for _, w := range strings.Fields(s) {
+		if t, ok := strings.CutPrefix(w, "tag:"); ok {
+			if t != "" {
+				tags = append(tags, t)
+			}
+			continue
+		}
+		words = append(words, w)
+	}
+	return tags, strings.Join(words, " ")
+}
+
 // SanitizeAndValidate will add missing defaults if necessary
 // (to prevent division by zero, for example). If there are crucial fields
 // missing (i.e. secret), it will return an error.
@@ -102,12 +205,59 @@ func (e *Entry) SanitizeAndValidate() error {
 		return ErrMissingSecret
 	}
 
-	if strings.ToUpper(e.Type) != "TOTP" {
+	switch strings.ToUpper(e.Type) {
+	case TOTP, HOTP:
+	case STEAM:
+		if e.Digits == 0 {
+			e.Digits = 5
+		}
+	case YANDEX:
+		if e.Digits == 0 {
+			e.Digits = 8
+		}
+		if e.Algorithm == "" {
+			e.Algorithm = "SHA256"
+		}
+	case MOTP, "MOBILE":
+		e.Type = MOTP
+		if e.Period == 0 {
+			e.Period = 10
+		}
+		if e.Algorithm == "" {
+			e.Algorithm = "MD5"
+		}
+	default:
 		log.Printf("%q: ignoring: %s", e.Issuer, e.Type)
 		return ErrInvalidType
 	}
 
-	if e.Period == 0 {
+	if e.requiresPin() && e.Pin == "" {
+		log.Printf("%q: ignoring: missing pin", e.Issuer)
+		return ErrMissingPin
+	}
+
+	if e.Type == MOTP {
+		if _, err := e.motpSecret(); err != nil {
+			log.Printf("%q: ignoring: %s", e.Issuer, err)
+			return ErrInvalidSecret
+		}
+	}
+
+	// the token generators panic on secrets that are no valid base32.
+	if t := strings.ToUpper(e.Type); t == TOTP || t == HOTP || t == STEAM {
+		e.Secret = NormalizeSecret(e.Secret)
+		if _, err := decodeSecret(e.Secret); err != nil || e.Secret == "" {
+			log.Printf("%q: ignoring: secret is no valid base32", e.Issuer)
+			return ErrInvalidSecret
+		}
+	}
+
+	if e.Counter < 0 {
+		log.Printf("%q: invalid counter, using default (0)", e.Issuer)
+		e.Counter = 0
+	}
+
+	if e.Period == 0 && !e.IsCounterBased() {
 		log.Printf("%q: missing period, using default (30)", e.Issuer)
 		e.Period = 30
 	}
@@ -125,24 +275,146 @@ func (e *Entry) SanitizeAndValidate() error {
 	return nil
 }
 
-// Find fuzzy filters a list of entries for s.
-func Find(s string, entries []Entry) (*Entry, error) {
-	stack := make([]string, 0, len(entries))
+// Match is a search result.
+type Match struct {
+	Index          int   // index of the matching entry
+	MatchedIndexes []int // matched characters of the entry filter value
+}
+
+// Search fuzzy matches s against the filter value of each entry. Entries
+// matching by title are ranked first, followed by entries matching by any
+// other field, each ordered by score.
+func Search(s string, entries []Entry) []Match {
+	titles := make([]string, 0, len(entries))
+	values := make([]string, 0, len(entries))
 	for _, e := range entries {
-		stack = append(stack, e.FilterValue())
+		titles = append(titles, e.Title())
+		values = append(values, e.FilterValue())
+	}
+
+	result := make([]Match, 0)
+	seen := make(map[int]bool)
+
+	// the title is the start of the filter value, so the matched
+	// indexes are valid for both.
+	for _, m := range fuzzy.Find(s, titles) {
+		result = append(result, Match{m.Index, m.MatchedIndexes})
+		seen[m.Index] = true
+	}
+
+	for _, m := range fuzzy.Find(s, values) {
+		if !seen[m.Index] {
+			result = append(result, Match{m.Index, m.MatchedIndexes})
+		}
+	}
+
+	return result
+}
+
+// Resolve returns the indexes of the entries matching the query s, best
+// match first. The query may contain tag filters ("tag:work") and may be
+// qualified as "issuer:label". Exact matches of title, username or
+// qualified name take precedence over fuzzy matches.
+func Resolve(s string, entries []Entry) []int {
+	tags, s := ParseFilter(s)
+	if s == "" && len(tags) == 0 {
+		return nil
+	}
+
+	candidates := withTags(entries, tags)
+	if s == "" {
+		return candidates
+	}
+
+	exact := make([]int, 0)
+	for _, i := range candidates {
+		e := entries[i]
+		if strings.EqualFold(s, e.Title()) ||
+			strings.EqualFold(s, e.Description()) ||
+			strings.EqualFold(s, e.QualifiedName()) {
+			exact = append(exact, i)
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact
+	}
+
+	if issuer, label, ok := strings.Cut(s, ":"); ok && issuer != "" && label != "" {
+		qualified := make([]int, 0)
+		for _, i := range candidates {
+			e := entries[i]
+			if fuzzy.Find(issuer, []string{e.Title()}).Len() > 0 &&
+				fuzzy.Find(label, []string{e.Description()}).Len() > 0 {
+				qualified = append(qualified, i)
+			}
+		}
+
+		if len(qualified) > 0 {
+			return qualified
+		}
+	}
+
+	return search(s, entries, candidates)
+}
+
+// Filter returns the entries matching the search s, best match first. Like
+// in Resolve, the search may contain tag filters. Unlike Resolve, there is
+// no preference for exact matches and an empty search matches all entries.
+func Filter(s string, entries []Entry) []Entry {
+	tags, s := ParseFilter(s)
+
+	candidates := withTags(entries, tags)
+	if s != "" {
+		candidates = search(s, entries, candidates)
+	}
+
+	result := make([]Entry, 0, len(candidates))
+	for _, i := range candidates {
+		result = append(result, entries[i])
 	}
+	return result
+}
+
+// Returns the indexes found by Search among the candidate indexes, in order.
+func search(s string, entries []Entry, candidates []int) []int {
+	subset := make([]Entry, 0, len(candidates))
+	for _, i := range candidates {
+		subset = append(subset, entries[i])
+	}
+
+	matches := make([]int, 0)
+	for _, m := range Search(s, subset) {
+		matches = append(matches, candidates[m.Index])
+	}
+	return matches
+}
+
+// Returns the indexes of the entries having all tags.
+func withTags(entries []Entry, tags []string) []int {
+	result := make([]int, 0, len(entries))
+	for i, e := range entries {
+		if !slices.ContainsFunc(tags, func(t string) bool { return !e.HasTag(t) }) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
 
-	matches := fuzzy.Find(s, stack)
+// Find returns the index of the entry matching the query s. See Resolve
+// for the query syntax. It fails if there is no or more than one match.
+func Find(s string, entries []Entry) (int, error) {
+	matches := Resolve(s, entries)
 	switch len(matches) {
 	case 0:
-		return nil, fmt.Errorf("no results for %q", s)
+		return -1, fmt.Errorf("no results for %q", s)
 	case 1:
-		return &entries[matches[0].Index], nil
+		return matches[0], nil
 	default:
 		hits := []string{}
-		for _, m := range matches {
-			hits = append(hits, m.Str)
+		for _, i := range matches {
+			hits = append(hits, entries[i].QualifiedName())
 		}
-		return nil, fmt.Errorf("multiple matches for %q: %s", s, strings.Join(hits, ", "))
+		return -1, fmt.Errorf("multiple matches for %q: %s", s, strings.Join(hits, ", "))
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -115,9 +116,12 @@ func TestEntry_FilterValue(t *testing.T) {
 		e    Entry
 		want string
 	}{
-		{"value: issuer", Entry{Label: "label", Issuer: "issuer"}, "issuer"},
+		{"value: issuer", Entry{Label: "label", Issuer: "issuer"}, "issuer label"},
 		{"value: label", Entry{Label: "label", Issuer: ""}, "label"},
-		{"value: label short", Entry{Label: "label - label2", Issuer: ""}, "label"},
+		{"value: label short", Entry{Label: "label - label2", Issuer: ""}, "label label2"},
+		{"value: vault", Entry{Issuer: "issuer", Vault: "vault.json"}, "issuer vault.json"},
+		{"value: tags", Entry{Issuer: "issuer", Tags: []string{"work", "mail"}, Vault: "vault.json"}, "issuer work mail vault.json"},
+		{"value: username", Entry{Issuer: "issuer", Label: "user@example.com", Tags: []string{"work"}}, "issuer user@example.com work"},
 	}
 
 	for _, tt := range tests {
@@ -129,6 +133,55 @@ func TestEntry_FilterValue(t *testing.T) {
 	}
 }
 
+func TestEntry_HasTag(t *testing.T) {
+	e := Entry{Tags: []string{"Work/Mail", "private"}}
+
+	tests := []struct {
+		name string
+		tag  string
+		want bool
+	}{
+		{"exact", "private", true},
+		{"case insensitive", "Private", true},
+		{"parent group", "work", true},
+		{"full path", "work/mail", true},
+		{"no prefix match", "priv", false},
+		{"missing", "mail", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.HasTag(tt.tag); got != tt.want {
+				t.Errorf("Entry.HasTag(%q) = %v, want %v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantTags []string
+		wantTerm string
+	}{
+		{"term only", "github", nil, "github"},
+		{"tag and term", "tag:work github", []string{"work"}, "github"},
+		{"multiple tags", "git tag:work  tag:dev", []string{"work", "dev"}, "git"},
+		{"empty tag", "tag: github", nil, "github"},
+		{"tag only", "tag:work", []string{"work"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, term := ParseFilter(tt.input)
+			if !reflect.DeepEqual(tags, tt.wantTags) || term != tt.wantTerm {
+				t.Errorf("ParseFilter() = %v, %q, want %v, %q", tags, term, tt.wantTags, tt.wantTerm)
+			}
+		})
+	}
+}
+
 func TestEntry_GenerateTOTP(t *testing.T) {
 	tests := []struct {
 		name string
@@ -172,6 +225,34 @@ func TestEntry_GenerateTOTP(t *testing.T) {
 	}
 }
 
+func TestEntry_GenerateHOTP(t *testing.T) {
+	// RFC 4226, Appendix D
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := []struct {
+		counter int
+		want    string
+	}{
+		{0, "755224"},
+		{1, "287082"},
+		{9, "520489"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.counter), func(t *testing.T) {
+			e := Entry{Secret: secret, Type: "HOTP", Digits: 6, Counter: tt.counter}
+			if got := e.GenerateHOTP(); got != tt.want {
+				t.Errorf("Entry.GenerateHOTP() = %v, want %v", got, tt.want)
+			}
+
+			got, exp := e.Generate()
+			if got != tt.want || exp != 0 {
+				t.Errorf("Entry.Generate() = %v, %v, want %v, 0", got, exp, tt.want)
+			}
+		})
+	}
+}
+
 func TestEntry_SanitizeAndValidate(t *testing.T) {
 	tests := []struct {
 		name       string // description of this test case
@@ -179,18 +260,61 @@ func TestEntry_SanitizeAndValidate(t *testing.T) {
 		fails      bool
 	}{
 		{"fails: missing secret", &Entry{Secret: ""}, nil, true},
-		{"fails: wrong type", &Entry{Secret: "123", Type: "HOTP"}, nil, true},
+		{"fails: wrong type", &Entry{Secret: "JBSWY3DP", Type: "UNKNOWN"}, nil, true},
+		{
+			"hotp: no default period",
+			&Entry{Secret: "JBSWY3DP", Type: "HOTP", Counter: 2},
+			&Entry{Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Digits: 6, Counter: 2},
+			false,
+		},
+		{
+			"steam: defaults",
+			&Entry{Secret: "JBSWY3DP", Type: "STEAM"},
+			&Entry{Secret: "JBSWY3DP", Type: "STEAM", Algorithm: "SHA1", Digits: 5, Period: 30},
+			false,
+		},
+		{
+			"yandex: defaults",
+			&Entry{Secret: "123", Pin: "1234", Type: "YANDEX"},
+			&Entry{Secret: "123", Pin: "1234", Type: "YANDEX", Algorithm: "SHA256", Digits: 8, Period: 30},
+			false,
+		},
+		{
+			"motp: defaults",
+			&Entry{Secret: "123", Pin: "1234", Type: "MOBILE", HexSecret: true},
+			&Entry{Secret: "123", Pin: "1234", Type: "MOTP", Algorithm: "MD5", Digits: 6, Period: 10, HexSecret: true},
+			false,
+		},
+		{"fails: missing pin", &Entry{Secret: "123", Type: "YANDEX"}, nil, true},
+		{"fails: invalid motp secret", &Entry{Secret: "not a secret!", Pin: "1234", Type: "MOTP"}, nil, true},
+		{"fails: invalid motp hex secret", &Entry{Secret: "JBSWY3DP", Pin: "1234", Type: "MOTP", HexSecret: true}, nil, true},
+		{"fails: invalid base32", &Entry{Secret: "not-base32!", Type: "TOTP"}, nil, true},
+		{"fails: invalid base32 length", &Entry{Secret: "JBSWY3", Type: "HOTP"}, nil, true},
+		{"fails: invalid steam secret", &Entry{Secret: "jbsw 1", Type: "STEAM"}, nil, true},
+		{"fails: padding only", &Entry{Secret: "====", Type: "TOTP"}, nil, true},
+		{
+			"totp: normalizes secret",
+			&Entry{Secret: "jbsw y3dp\tehpk 3pxp====", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+			&Entry{Secret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+			false,
+		},
+		{
+			"hotp: resets negative counter",
+			&Entry{Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Digits: 6, Counter: -1},
+			&Entry{Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Digits: 6, Counter: 0},
+			false,
+		},
 		{
 			"defaults: period",
 			&Entry{
-				Secret:    "123",
+				Secret:    "JBSWY3DP",
 				Type:      "TOTP",
 				Period:    0,
 				Algorithm: "SHA1",
 				Digits:    6,
 			},
 			&Entry{
-				Secret:    "123",
+				Secret:    "JBSWY3DP",
 				Type:      "TOTP",
 				Period:    30,
 				Algorithm: "SHA1",
@@ -201,14 +325,14 @@ func TestEntry_SanitizeAndValidate(t *testing.T) {
 		{
 			"defaults: algorithm",
 			&Entry{
-				Secret:    "123",
+				Secret:    "JBSWY3DP",
 				Type:      "TOTP",
 				Period:    30,
 				Algorithm: "",
 				Digits:    6,
 			},
 			&Entry{
-				Secret:    "123",
+				Secret:    "JBSWY3DP",
 				Type:      "TOTP",
 				Period:    30,
 				Algorithm: "SHA1",
@@ -219,14 +343,14 @@ func TestEntry_SanitizeAndValidate(t *testing.T) {
 		{
 			"defaults: digits",
 			&Entry{
-				Secret:    "123",
+				Secret:    "JBSWY3DP",
 				Type:      "TOTP",
 				Period:    30,
 				Algorithm: "SHA1",
 				Digits:    0,
 			},
 			&Entry{
-				Secret:    "123",
+				Secret:    "JBSWY3DP",
 				Type:      "TOTP",
 				Period:    30,
 				Algorithm: "SHA1",
@@ -263,18 +387,19 @@ func TestFind(t *testing.T) {
 
 	tests := []struct {
 		query   string
-		want    *Entry
+		want    int
 		wantErr bool
 	}{
-		{"Google", &entries[0], false},
-		{"goog", &entries[0], false},
-		{"GITHUB", &entries[1], false},
-		{"git", nil, true},
-		{"nomatch", nil, true},
-		{"", nil, true},
-		{"white space", &entries[3], false},
-		{"~", &entries[4], false},
-		{"[something]", nil, true},
+		{"Google", 0, false},
+		{"goog", 0, false},
+		{"GITHUB", 1, false},
+		{"git", -1, true},
+		{"nomatch", -1, true},
+		{"", -1, true},
+		{"white space", 3, false},
+		{"~", 4, false},
+		{"[something]", -1, true},
+		{"user@github", 1, false},
 	}
 
 	for _, tt := range tests {
@@ -284,9 +409,258 @@ func TestFind(t *testing.T) {
 				t.Errorf("Find() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			if got != tt.want {
 				t.Errorf("Find() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestResolve(t *testing.T) {
+	entries := []Entry{
+		{Issuer: "GitHub", Label: "alice"},
+		{Issuer: "GitHub", Label: "bob", Tags: []string{"work"}},
+		{Issuer: "GitLab", Label: "alice"},
+		{Issuer: "GitHub Enterprise", Label: "carol"},
+		{Issuer: "Host:8080", Label: "dave"},
+		{Issuer: "Twin", Label: "erin"},
+		{Issuer: "Twin", Label: "erin"},
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"git", []int{0, 1, 2, 3}},
+		{"github", []int{0, 1}},
+		{"GITLAB", []int{2}},
+		{"carol", []int{3}},
+		{"github:bob", []int{1}},
+		{"gh:ali", []int{0}},
+		{"git:alice", []int{0, 2}},
+		{"tag:work git", []int{1}},
+		{"tag:work", []int{1}},
+		{"host:8080", []int{4}},
+		{"host:80", []int{4}},
+		{"twin", []int{5, 6}},
+		{"", []int{}},
+		{"nomatch", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := Resolve(tt.query, entries)
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entries := []Entry{
+		{Issuer: "GitHub", Label: "alice"},
+		{Issuer: "GitHub", Label: "bob", Tags: []string{"work"}},
+		{Issuer: "GitHub Enterprise", Label: "carol", Tags: []string{"work"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", []int{0, 1, 2}},
+		{"github", []int{0, 1, 2}},
+		{"tag:work", []int{1, 2}},
+		{"tag:work enterprise", []int{2}},
+		{"nomatch", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			want := make([]Entry, 0)
+			for _, i := range tt.want {
+				want = append(want, entries[i])
+			}
+
+			if got := Filter(tt.query, entries); !reflect.DeepEqual(got, want) {
+				t.Errorf("Filter() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestEntry_QualifiedName(t *testing.T) {
+	tests := []struct {
+		name string
+		e    Entry
+		want string
+	}{
+		{"issuer and label", Entry{Issuer: "issuer", Label: "label"}, "issuer:label"},
+		{"issuer only", Entry{Issuer: "issuer"}, "issuer"},
+		{"label only", Entry{Label: "label"}, "label"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.QualifiedName(); got != tt.want {
+				t.Errorf("Entry.QualifiedName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	entries := []Entry{
+		{Issuer: "Google", Label: "work@example.com", Tags: []string{"work"}},
+		{Issuer: "Mail", Label: "user@gmail.com"},
+		{Issuer: "Google", Label: "private@example.com"},
+		{Issuer: "Workspace", Label: "user"},
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"google", []int{0, 2}},
+		{"gmail", []int{1}},
+		{"private", []int{2}},
+		{"work", []int{3, 0}},
+		{"nomatch", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := make([]int, 0)
+			for _, m := range Search(tt.query, entries) {
+				got = append(got, m.Index)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_GenerateNext(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	t.Run("hotp", func(t *testing.T) {
+		e := Entry{Secret: secret, Type: "HOTP", Digits: 6, Counter: 0}
+		if got := e.GenerateNext(); got != "287082" {
+			t.Errorf("Entry.GenerateNext() = %v, want %v", got, "287082")
+		}
+	})
+
+	// the next token is valid from the expiration time of the current one.
+	t.Run("totp", func(t *testing.T) {
+		e := Entry{Secret: secret, Type: "TOTP", Digits: 6, Period: 30}
+		_, exp := e.Generate()
+		want := gotp.NewTOTP(secret, 6, 30, e.hasher()).At(exp)
+		if got := e.GenerateNext(); got != want {
+			t.Errorf("Entry.GenerateNext() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("steam", func(t *testing.T) {
+		e := Entry{Secret: secret, Type: "STEAM", Digits: 5, Period: 30}
+		_, exp := e.Generate()
+		want := e.steamAt(exp / 30)
+		if got := e.GenerateNext(); got != want {
+			t.Errorf("Entry.GenerateNext() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestEntry_nextAt(t *testing.T) {
+	// RFC 6238, Appendix B: the next token at t is the token at t+30.
+	e := Entry{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: "TOTP", Algorithm: "SHA1", Digits: 8, Period: 30}
+
+	tests := []struct {
+		now  int64
+		want string
+	}{
+		{59 - 30, "94287082"},
+		{1111111109 - 30, "07081804"},
+		{1111111111 - 30, "14050471"},
+		{1234567890 - 30, "89005924"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.now), func(t *testing.T) {
+			if got := e.nextAt(tt.now); got != tt.want {
+				t.Errorf("Entry.nextAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_steamAt(t *testing.T) {
+	e := Entry{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: "STEAM", Digits: 5}
+
+	tests := []struct {
+		counter int64
+		want    string
+	}{
+		{0, "GG5F5"},
+		{1, "PV9M4"},
+		{59, "QTVBC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.counter), func(t *testing.T) {
+			if got := e.steamAt(tt.counter); got != tt.want {
+				t.Errorf("Entry.steamAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_yandexAt(t *testing.T) {
+	e := Entry{Secret: "LA2V6KMCGYMWWVEW64RNP3JA3I", Pin: "5239", Type: "YANDEX", Digits: 8}
+
+	tests := []struct {
+		counter int64
+		want    string
+	}{
+		{0, "uqqovpej"},
+		{1, "scpivigv"},
+		{56666666, "sazqniwh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.counter), func(t *testing.T) {
+			if got := e.yandexAt(tt.counter); got != tt.want {
+				t.Errorf("Entry.yandexAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_motpAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		hex     bool
+		counter int64
+		want    string
+	}{
+		{"base32: 0", "GEZDGNBVGY3TQOJQ", false, 0, "56ac9b"},
+		{"base32: 1", "GEZDGNBVGY3TQOJQ", false, 1, "7f31ba"},
+		{"base32: 170000000", "GEZDGNBVGY3TQOJQ", false, 170000000, "8dcd49"},
+		{"base32: hex characters only", "ABCDEF23", false, 0, "7224d1"},
+		{"hex: keeps case", "ABCDEF0123456789", true, 0, "eb0cf4"},
+		{"hex: keeps case 1", "ABCDEF0123456789", true, 1, "c70f28"},
+		{"hex: odd length", "12345", true, 0, "24007f"},
+		{"fails: invalid secret", "not a secret!", false, 0, ""},
+		{"fails: invalid hex secret", "GEZDGNBVGY3TQOJQ", true, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Entry{Secret: tt.secret, Pin: "1234", Type: "MOTP", Digits: 6, HexSecret: tt.hex}
+			if got := e.motpAt(tt.counter); got != tt.want {
+				t.Errorf("Entry.motpAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package freeotp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const vaultType = vaults.FREEOTP
+
+const gcmStandardNonceSize = 12
+
+var _ vaults.Vault = &freeotp{}
+
+var (
+	javaMagic          = []byte{0xac, 0xed, 0x00, 0x05}
+	errMissingKey      = errors.New("missing master key")
+	errInvalidPassword = errors.New("invalid password")
+	errGCMParameters   = errors.New("unsupported GCM parameters")
+)
+
+type (
+	freeotp struct{ tokens []token }
+
+	// masterKey is the password protected key which encrypts all secrets.
+	masterKey struct {
+		Algorithm    string       `json:"mAlgorithm"`
+		EncryptedKey encryptedKey `json:"mEncryptedKey"`
+		Iterations   int          `json:"mIterations"`
+		Salt         []int8       `json:"mSalt"`
+	}
+
+	// encryptedKey is an AES-GCM encrypted key, the key algorithm is used
+	// as additional data.
+	encryptedKey struct {
+		Cipher     string `json:"mCipher"`
+		CipherText []int8 `json:"mCipherText"`
+		Parameters []int8 `json:"mParameters"`
+		Token      string `json:"mToken"`
+	}
+
+	token struct {
+		Algo                 string
+		Counter              int
+		Digits               int
+		IssuerExt, IssuerInt string
+		Label                string
+		Period               int
+		Type                 string
+		//
+		secret []byte
+	}
+
+	// DER encoded GCMParameters (RFC 5084). The tag length is in bytes.
+	gcmParameters struct {
+		Nonce  []byte
+		TagLen int `asn1:"optional,default:12"`
+	}
+)
+
+func Open(filename string, pass []byte) (vaults.Vault, error) {
+	m, err := read(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	var mk masterKey
+	if err := json.Unmarshal([]byte(m["masterKey"]), &mk); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", vaultType, errMissingKey, err)
+	}
+
+	key, err := mk.decrypt(pass)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	v := &freeotp{}
+	for k, s := range m {
+		uuid, ok := strings.CutSuffix(k, "-token")
+		if !ok {
+			continue
+		}
+
+		t, err := decryptToken(s, m[uuid], key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", vaultType, uuid, err)
+		}
+		v.tokens = append(v.tokens, t)
+	}
+
+	// map order is random.
+	slices.SortFunc(v.tokens, func(a, b token) int {
+		return strings.Compare(a.IssuerExt+a.Label, b.IssuerExt+b.Label)
+	})
+
+	return v, nil
+}
+
+// IsEncrypted always returns true for a valid file: all FreeOTP backups
+// are protected by a password.
+func IsEncrypted(filename string) (bool, error) {
+	if _, err := read(filename); err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return true, nil
+}
+
+func (v freeotp) Entries() []vaults.Entry {
+	entries := make([]vaults.Entry, 0)
+
+	for _, t := range v.tokens {
+		issuer := t.IssuerExt
+		if issuer == "" {
+			issuer = t.IssuerInt
+		}
+
+		entry := vaults.Entry{
+			Secret:    base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(t.secret),
+			Issuer:    issuer,
+			Label:     t.Label,
+			Type:      strings.ToUpper(t.Type),
+			Algorithm: t.Algo,
+			Digits:    t.Digits,
+			Period:    t.Period,
+			Counter:   t.Counter,
+		}
+
+		if err := entry.SanitizeAndValidate(); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Derives a key from the password and decrypts the master key.
+func (mk masterKey) decrypt(pass []byte) ([]byte, error) {
+	var h func() hash.Hash
+
+	switch strings.ToLower(mk.Algorithm) {
+	case "pbkdf2withhmacsha512":
+		h = sha512.New
+	case "pbkdf2withhmacsha256":
+		h = sha256.New
+	case "pbkdf2withhmacsha1":
+		h = sha1.New
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q", mk.Algorithm)
+	}
+
+	key := pbkdf2.Key(pass, bytesOf(mk.Salt), mk.Iterations, 32, h)
+
+	b, err := mk.EncryptedKey.decrypt(key)
+	if errors.Is(err, errGCMParameters) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errInvalidPassword
+	}
+
+	return b, nil
+}
+
+// Decrypts the key with AES-GCM.
+func (ek encryptedKey) decrypt(key []byte) ([]byte, error) {
+	var params gcmParameters
+	if _, err := asn1.Unmarshal(bytesOf(ek.Parameters), &params); err != nil {
+		return nil, fmt.Errorf("%w: %s", errGCMParameters, err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Go supports either a custom nonce or a custom tag size, FreeOTP uses
+	// the standard 12 byte nonce and 16 byte tag.
+	var gcm cipher.AEAD
+	switch {
+	case len(params.Nonce) == gcmStandardNonceSize && params.TagLen >= 12 && params.TagLen <= 16:
+		gcm, err = cipher.NewGCMWithTagSize(block, params.TagLen)
+	case params.TagLen == 16:
+		gcm, err = cipher.NewGCMWithNonceSize(block, len(params.Nonce))
+	default:
+		return nil, fmt.Errorf("%w: %d byte nonce with %d byte tag", errGCMParameters, len(params.Nonce), params.TagLen)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errGCMParameters, err)
+	}
+
+	return gcm.Open(nil, params.Nonce, bytesOf(ek.CipherText), []byte(ek.Token))
+}
+
+// Parses the token metadata and decrypts its secret. The encrypted key is
+// stored as JSON string inside of another JSON object.
+func decryptToken(meta, enc string, key []byte) (token, error) {
+	var t token
+	if err := json.Unmarshal([]byte(meta), &t); err != nil {
+		return t, err
+	}
+
+	var wrapper struct{ Key string }
+	if err := json.Unmarshal([]byte(enc), &wrapper); err != nil {
+		return t, err
+	}
+
+	var ek encryptedKey
+	if err := json.Unmarshal([]byte(wrapper.Key), &ek); err != nil {
+		return t, err
+	}
+
+	secret, err := ek.decrypt(key)
+	if err != nil {
+		return t, err
+	}
+	t.secret = secret
+
+	return t, nil
+}
+
+// Converts a signed java byte array.
+func bytesOf(b []int8) []byte {
+	out := make([]byte, len(b))
+	for i, v := range b {
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// reads the serialized backup map.
+func read(filename string) (map[string]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(b, javaMagic) {
+		return nil, errUnsupportedStream
+	}
+
+	return decodeMap(bytes.NewReader(b))
+}
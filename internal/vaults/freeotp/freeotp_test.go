@@ -0,0 +1,233 @@
+package freeotp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/asn1"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		password string
+		fails    bool
+	}{
+		{"decrypts", "testdata/freeotp-backup.xml", "andcli-test", false},
+		{"fails: wrong password", "testdata/freeotp-backup.xml", "invalid", true},
+		{"fails: invalid file", "../freeotpplus/testdata/freeotpplus-test.json", "andcli-test", true},
+		{"fails: missing file", "testdata/nosuchfile.xml", "andcli-test", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Open(tt.filename, []byte(tt.password))
+			if tt.fails {
+				if err == nil {
+					t.Fatal("Open() expected error, got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries := v.Entries()
+			if len(entries) != 3 {
+				t.Fatalf("Open() expected len to be 3, have %v", len(entries))
+			}
+
+			want := []struct {
+				label, typ, secret string
+			}{
+				{"demo1", "TOTP", "BIS22DXNONV3JPIRGF6BQMT27GLXZGJTQAIEMMGZKWYH7FJRVDSQ"},
+				{"demo2", "TOTP", "LS6OKJ4XGSREK73DPR4ACOGFYU5EBQXMNXQVSIDKXDLDZVDBMTKQ"},
+				{"demo3", "HOTP", "YPGQF3WUM4P6LSP7J5PUM42J63KCTHYKPD2GEX2EH4DQ7452EAOA"},
+			}
+
+			for i, w := range want {
+				e := entries[i]
+				if e.Label != w.label || e.Type != w.typ || e.Secret != w.secret {
+					t.Fatalf("Open() have %v, want %v", e, w)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/freeotp-backup.xml", true, false},
+		{"fails: invalid file", "../freeotpplus/testdata/freeotpplus-test.json", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMap(t *testing.T) {
+	str := func(s string) []byte {
+		return append([]byte{tcString, 0, byte(len(s))}, s...)
+	}
+
+	hashMap := func(content ...[]byte) []byte {
+		b := []byte{0xac, 0xed, 0x00, 0x05, tcObject, tcClassDesc, 0x00, 0x11}
+		b = append(b, "java.util.HashMap"...)
+		b = append(b, 0x05, 0x07, 0xda, 0xc1, 0xc3, 0x16, 0x60, 0xd1, 0x03, 0x00, 0x02)
+		b = append(b, 'F', 0x00, 0x0a)
+		b = append(b, "loadFactor"...)
+		b = append(b, 'I', 0x00, 0x09)
+		b = append(b, "threshold"...)
+		b = append(b, tcEndBlockData, tcNull)
+		b = append(b, 0x3f, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c)
+		b = append(b, tcBlockData, 0x08, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x02)
+		return append(append(b, bytes.Join(content, nil)...), tcEndBlockData)
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  map[string]string
+		fails bool
+	}{
+		{
+			"decodes strings",
+			hashMap(str("a"), str("1"), str("b"), str("2")),
+			map[string]string{"a": "1", "b": "2"},
+			false,
+		},
+		{
+			"resolves references",
+			// handles: class desc, map, "a", "1"
+			hashMap(str("a"), str("1"), str("b"), []byte{tcReference, 0x00, 0x7e, 0x00, 0x03}),
+			map[string]string{"a": "1", "b": "1"},
+			false,
+		},
+		{"fails: odd number of strings", hashMap(str("a")), nil, true},
+		{"fails: unknown reference", hashMap(str("a"), []byte{tcReference, 0x00, 0x7e, 0x00, 0x09}), nil, true},
+		{"fails: unsupported content", hashMap([]byte{tcObject}), nil, true},
+		{"fails: truncated", hashMap(str("a"), str("1"))[:60], nil, true},
+		{
+			"fails: truncated long string",
+			hashMap([]byte{tcLongString, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 'a'}),
+			nil,
+			true,
+		},
+		{
+			"fails: long string out of range",
+			hashMap([]byte{tcLongString, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 'a'}),
+			nil,
+			true,
+		},
+		{"fails: truncated long block", hashMap([]byte{tcBlockLong, 0xff, 0xff, 0xff, 0xff, 0x00}), nil, true},
+		{"fails: magic", []byte{0xca, 0xfe, 0xba, 0xbe}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeMap(bytes.NewReader(tt.input))
+			if (err != nil) != tt.fails {
+				t.Fatalf("decodeMap() error = %v, wantErr %v", err, tt.fails)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptedKey_decrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	plain := []byte("secret")
+
+	encrypt := func(nonce []byte, tagLen int) encryptedKey {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gcm, err := cipher.NewGCMWithTagSize(block, tagLen)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		params, err := asn1.Marshal(gcmParameters{Nonce: nonce, TagLen: tagLen})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return encryptedKey{
+			CipherText: int8sOf(gcm.Seal(nil, nonce, plain, []byte("AES"))),
+			Parameters: int8sOf(params),
+			Token:      "AES",
+		}
+	}
+
+	nonce := bytes.Repeat([]byte{2}, 12)
+
+	// the parameters of a 16 byte tag, with another tag length.
+	withTagLen := func(tagLen int) encryptedKey {
+		ek := encrypt(nonce, 16)
+		params, err := asn1.Marshal(gcmParameters{Nonce: nonce, TagLen: tagLen})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ek.Parameters = int8sOf(params)
+		return ek
+	}
+
+	tests := []struct {
+		name  string
+		ek    encryptedKey
+		fails bool
+		err   error
+	}{
+		{"16 byte tag", encrypt(nonce, 16), false, nil},
+		{"12 byte tag", encrypt(nonce, 12), false, nil},
+		{"fails: wrong tag length", withTagLen(12), true, nil},
+		{"fails: unsupported tag length", withTagLen(8), true, errGCMParameters},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ek.decrypt(key)
+			if (err != nil) != tt.fails {
+				t.Fatalf("decrypt() error = %v, wantErr %v", err, tt.fails)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("decrypt() error = %v, want %v", err, tt.err)
+			}
+			if !tt.fails && !bytes.Equal(got, plain) {
+				t.Errorf("decrypt() = %q, want %q", got, plain)
+			}
+		})
+	}
+}
+
+// Converts bytes to a signed java byte array, the counterpart to bytesOf.
+func int8sOf(b []byte) []int8 {
+	out := make([]int8, len(b))
+	for i, v := range b {
+		out[i] = int8(v)
+	}
+	return out
+}
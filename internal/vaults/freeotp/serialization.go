@@ -0,0 +1,269 @@
+package freeotp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Java object serialization constants, see
+// https://docs.oracle.com/javase/8/docs/platform/serialization/spec/protocol.html
+const (
+	streamMagic   = 0xaced
+	streamVersion = 5
+	baseHandle    = 0x7e0000
+
+	tcNull         = 0x70
+	tcReference    = 0x71
+	tcClassDesc    = 0x72
+	tcObject       = 0x73
+	tcString       = 0x74
+	tcBlockData    = 0x77
+	tcEndBlockData = 0x78
+	tcBlockLong    = 0x7a
+	tcLongString   = 0x7c
+
+	scWriteMethod = 0x01
+)
+
+var errUnsupportedStream = errors.New("unsupported serialization stream")
+
+// primitive field sizes by type code.
+var primitiveSize = map[byte]int{
+	'B': 1, 'C': 2, 'D': 8, 'F': 4, 'I': 4, 'J': 8, 'S': 2, 'Z': 1,
+}
+
+// decoder reads a serialized java.util.HashMap<String, String>, which is
+// all a FreeOTP backup consists of. Anything else is rejected.
+type decoder struct {
+	r       *bufio.Reader
+	handles map[int]string
+	next    int
+}
+
+// Returns the key value pairs of a serialized string map.
+func decodeMap(r io.Reader) (map[string]string, error) {
+	d := &decoder{r: bufio.NewReader(r), handles: make(map[int]string), next: baseHandle}
+
+	var head struct{ Magic, Version uint16 }
+	if err := binary.Read(d.r, binary.BigEndian, &head); err != nil {
+		return nil, err
+	}
+
+	if head.Magic != streamMagic || head.Version != streamVersion {
+		return nil, errUnsupportedStream
+	}
+
+	if tc, err := d.r.ReadByte(); err != nil || tc != tcObject {
+		return nil, errUnsupportedStream
+	}
+
+	fields, flags, err := d.classDesc()
+	if err != nil {
+		return nil, err
+	}
+
+	if flags&scWriteMethod == 0 {
+		return nil, errUnsupportedStream
+	}
+	d.newHandle("")
+
+	// default field values (loadFactor, threshold), which are not needed.
+	for _, typ := range fields {
+		if _, err := d.r.Discard(primitiveSize[typ]); err != nil {
+			return nil, err
+		}
+	}
+
+	// the object annotation holds capacity and size as block data,
+	// followed by the keys and values.
+	strs, err := d.annotation()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strs)%2 != 0 {
+		return nil, errUnsupportedStream
+	}
+
+	m := make(map[string]string, len(strs)/2)
+	for i := 0; i < len(strs); i += 2 {
+		m[strs[i]] = strs[i+1]
+	}
+
+	return m, nil
+}
+
+// Reads a class description including its super classes. Returns the
+// primitive field type codes and the class flags.
+func (d *decoder) classDesc() ([]byte, byte, error) {
+	tc, err := d.r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch tc {
+	case tcNull:
+		return nil, 0, nil
+	case tcClassDesc:
+	default:
+		return nil, 0, errUnsupportedStream
+	}
+
+	if _, err := d.utf(); err != nil { // class name
+		return nil, 0, err
+	}
+
+	if _, err := d.r.Discard(8); err != nil { // serialVersionUID
+		return nil, 0, err
+	}
+	d.newHandle("")
+
+	flags, err := d.r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var count uint16
+	if err := binary.Read(d.r, binary.BigEndian, &count); err != nil {
+		return nil, 0, err
+	}
+
+	fields := make([]byte, 0, count)
+	for range count {
+		typ, err := d.r.ReadByte()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if _, err := d.utf(); err != nil { // field name
+			return nil, 0, err
+		}
+
+		if _, ok := primitiveSize[typ]; !ok {
+			return nil, 0, fmt.Errorf("%w: object field", errUnsupportedStream)
+		}
+		fields = append(fields, typ)
+	}
+
+	if _, err := d.annotation(); err != nil {
+		return nil, 0, err
+	}
+
+	super, _, err := d.classDesc()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// super class values come first.
+	return append(super, fields...), flags, nil
+}
+
+// Reads annotation contents until the end marker. Block data is skipped,
+// all strings are returned in order.
+func (d *decoder) annotation() ([]string, error) {
+	var strs []string
+
+	for {
+		tc, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tc {
+		case tcEndBlockData:
+			return strs, nil
+		case tcBlockData:
+			n, err := d.r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := d.r.Discard(int(n)); err != nil {
+				return nil, err
+			}
+		case tcBlockLong:
+			var n uint32
+			if err := binary.Read(d.r, binary.BigEndian, &n); err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(io.Discard, d.r, int64(n)); err != nil {
+				return nil, errUnsupportedStream
+			}
+		case tcString, tcLongString:
+			s, err := d.string(tc)
+			if err != nil {
+				return nil, err
+			}
+			strs = append(strs, s)
+		case tcReference:
+			var h uint32
+			if err := binary.Read(d.r, binary.BigEndian, &h); err != nil {
+				return nil, err
+			}
+			s, ok := d.handles[int(h)]
+			if !ok {
+				return nil, errUnsupportedStream
+			}
+			strs = append(strs, s)
+		default:
+			return nil, fmt.Errorf("%w: type code %#x", errUnsupportedStream, tc)
+		}
+	}
+}
+
+// Reads a new string object. Strings are stored as modified UTF-8, which
+// equals UTF-8 for the JSON content used by FreeOTP. The length is read
+// from the file, so the content is copied instead of allocated upfront: a
+// truncated stream fails on its actual size.
+func (d *decoder) string(tc byte) (string, error) {
+	var n uint64
+
+	if tc == tcLongString {
+		if err := binary.Read(d.r, binary.BigEndian, &n); err != nil {
+			return "", err
+		}
+	} else {
+		var l uint16
+		if err := binary.Read(d.r, binary.BigEndian, &l); err != nil {
+			return "", err
+		}
+		n = uint64(l)
+	}
+
+	if n > math.MaxInt64 {
+		return "", errUnsupportedStream
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.r, int64(n)); err != nil {
+		return "", errUnsupportedStream
+	}
+
+	d.newHandle(buf.String())
+	return buf.String(), nil
+}
+
+// Reads a length prefixed string which is not an object.
+func (d *decoder) utf() (string, error) {
+	var n uint16
+	if err := binary.Read(d.r, binary.BigEndian, &n); err != nil {
+		return "", err
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Assigns the next handle. Only strings are ever referenced.
+func (d *decoder) newHandle(s string) {
+	d.handles[d.next] = s
+	d.next++
+}
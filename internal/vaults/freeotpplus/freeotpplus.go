@@ -0,0 +1,130 @@
+package freeotpplus
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+const vaultType = vaults.FREEOTPPLUS
+
+var _ vaults.Vault = &freeotpplus{}
+
+type (
+	freeotpplus struct {
+		TokenOrder []string
+		Tokens     []token
+	}
+
+	token struct {
+		Algo                 string
+		Counter              int
+		Digits               int
+		IssuerExt, IssuerInt string
+		Label                string
+		Period               int
+		Secret               []int8 // java bytes are signed
+		Type                 string
+	}
+)
+
+func Open(filename string, _ []byte) (vaults.Vault, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	v := new(freeotpplus)
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return v, nil
+}
+
+// IsEncrypted always returns false: FreeOTP+ exports are not protected.
+func IsEncrypted(filename string) (bool, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return false, nil
+}
+
+func (v freeotpplus) Entries() []vaults.Entry {
+	entries := make([]vaults.Entry, 0)
+
+	for _, t := range v.sorted() {
+		issuer := t.IssuerExt
+		if issuer == "" {
+			issuer = t.IssuerInt
+		}
+
+		entry := vaults.Entry{
+			Secret:    encodeSecret(t.Secret),
+			Issuer:    issuer,
+			Label:     t.Label,
+			Type:      strings.ToUpper(t.Type),
+			Algorithm: t.Algo,
+			Digits:    t.Digits,
+			Period:    t.Period,
+			Counter:   t.Counter,
+		}
+
+		if err := entry.SanitizeAndValidate(); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Returns the tokens in the order of the app. Tokens missing in the order
+// follow in file order.
+func (v freeotpplus) sorted() []token {
+	pos := make(map[string]int, len(v.TokenOrder))
+	for i, id := range v.TokenOrder {
+		if _, ok := pos[id]; !ok {
+			pos[id] = i
+		}
+	}
+
+	index := func(t token) int {
+		if i, ok := pos[t.id()]; ok {
+			return i
+		}
+		return len(v.TokenOrder)
+	}
+
+	tokens := slices.Clone(v.Tokens)
+	slices.SortStableFunc(tokens, func(a, b token) int { return index(a) - index(b) })
+
+	return tokens
+}
+
+// Returns the id of the token in the token order, "issuer:label" or the
+// label only. Like in the app, the internal issuer takes precedence.
+func (t token) id() string {
+	issuer := t.IssuerInt
+	if issuer == "" {
+		issuer = t.IssuerExt
+	}
+
+	if issuer == "" {
+		return t.Label
+	}
+	return issuer + ":" + t.Label
+}
+
+// Converts a signed byte array into a base32 secret.
+func encodeSecret(secret []int8) string {
+	b := make([]byte, len(secret))
+	for i, v := range secret {
+		b[i] = byte(v)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+}
@@ -0,0 +1,120 @@
+package freeotpplus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		fails    bool
+	}{
+		{"opens", "testdata/freeotpplus-test.json", false},
+		{"fails: invalid file", "../freeotp/testdata/freeotp-backup.xml", true},
+		{"fails: missing file", "testdata/nosuchfile.json", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Open(tt.filename, nil)
+			if tt.fails {
+				if err == nil {
+					t.Fatal("Open() expected error, got none")
+				}
+				return
+			}
+
+			entries := v.Entries()
+			if len(entries) != 3 {
+				t.Fatalf("Open() expected len to be 3, have %v", len(entries))
+			}
+
+			want := []string{
+				"BIS22DXNONV3JPIRGF6BQMT27GLXZGJTQAIEMMGZKWYH7FJRVDSQ",
+				"LS6OKJ4XGSREK73DPR4ACOGFYU5EBQXMNXQVSIDKXDLDZVDBMTKQ",
+				"YPGQF3WUM4P6LSP7J5PUM42J63KCTHYKPD2GEX2EH4DQ7452EAOA",
+			}
+
+			for i, secret := range want {
+				if entries[i].Secret != secret {
+					t.Fatalf("Open() have %v, want %s", entries[i].Secret, secret)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		fails    bool
+	}{
+		{"plain", "testdata/freeotpplus-test.json", false},
+		{"fails: missing file", "testdata/nosuchfile.json", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got {
+				t.Errorf("IsEncrypted() = %v, want false", got)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []string
+		input []token
+		want  []vaults.Entry
+	}{
+		{
+			"converts signed secrets, mitigates missing fields",
+			nil,
+			[]token{
+				{IssuerExt: "iss-1", Label: "demo1", Secret: []int8{72, 101, 108, 108, 111, 33, -34, -83, -66, -17}, Type: "totp"},
+				{IssuerInt: "iss-2", Label: "demo2", Secret: []int8{72, 101}, Type: "HOTP", Counter: 3, Digits: 8},
+				{IssuerExt: "iss-3", Label: "demo3", Type: "TOTP"},
+			},
+			[]vaults.Entry{
+				{Issuer: "iss-1", Label: "demo1", Secret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Issuer: "iss-2", Label: "demo2", Secret: "JBSQ", Type: "HOTP", Algorithm: "SHA1", Digits: 8, Counter: 3},
+			},
+		},
+		{
+			"sorts by token order, unordered last",
+			[]string{"int-3:demo3", "demo4", "iss-1:demo1", "unknown:demo"},
+			[]token{
+				{IssuerExt: "iss-1", Label: "demo1", Secret: []int8{72, 101}, Type: "TOTP"},
+				{IssuerExt: "iss-2", Label: "demo2", Secret: []int8{72, 101}, Type: "TOTP"},
+				{IssuerExt: "iss-3", IssuerInt: "int-3", Label: "demo3", Secret: []int8{72, 101}, Type: "TOTP"},
+				{Label: "demo4", Secret: []int8{72, 101}, Type: "TOTP"},
+			},
+			[]vaults.Entry{
+				{Issuer: "iss-3", Label: "demo3", Secret: "JBSQ", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Label: "demo4", Secret: "JBSQ", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Issuer: "iss-1", Label: "demo1", Secret: "JBSQ", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Issuer: "iss-2", Label: "demo2", Secret: "JBSQ", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := freeotpplus{TokenOrder: tt.order, Tokens: tt.input}.Entries()
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package googleauth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+const vaultType = vaults.GOOGLE
+
+var _ vaults.Vault = &googleauth{}
+
+var errNoData = errors.New("no migration data found")
+
+// googleauth is a list of otpauth-migration:// URIs, one per line, as
+// contained in the QR codes of a Google Authenticator export. Large exports
+// are split into several batches.
+type googleauth struct{ batches []*vaults.Migration }
+
+func Open(filename string, _ []byte) (vaults.Vault, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	v := &googleauth{}
+	for _, line := range vaults.Lines(b) {
+		m, err := vaults.ParseMigrationURI(line.Text)
+		if err != nil {
+			log.Printf("line %d: %s", line.Number, err)
+			continue
+		}
+		v.batches = append(v.batches, m)
+	}
+
+	if len(v.batches) == 0 {
+		return nil, fmt.Errorf("%s: %w", vaultType, errNoData)
+	}
+
+	v.checkBatches()
+
+	return v, nil
+}
+
+// IsEncrypted always returns false: migration exports are not protected.
+func IsEncrypted(filename string) (bool, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return false, fmt.Errorf("%s: %s", vaultType, err)
+	}
+	return false, nil
+}
+
+func (v googleauth) Entries() []vaults.Entry {
+	entries := make([]vaults.Entry, 0)
+
+	for _, m := range v.batches {
+		for _, e := range m.Entries {
+			if err := e.SanitizeAndValidate(); err == nil {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	return entries
+}
+
+// Logs a warning for each export with missing batches.
+func (v googleauth) checkBatches() {
+	seen := make(map[int]map[int]bool)
+	size := make(map[int]int)
+
+	for _, m := range v.batches {
+		if seen[m.BatchID] == nil {
+			seen[m.BatchID] = make(map[int]bool)
+		}
+		seen[m.BatchID][m.BatchIndex] = true
+		size[m.BatchID] = m.BatchSize
+	}
+
+	for id, indexes := range seen {
+		if len(indexes) < size[id] {
+			log.Printf("export %d: found %d of %d batches", id, len(indexes), size[id])
+		}
+	}
+}
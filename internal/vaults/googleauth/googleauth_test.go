@@ -0,0 +1,82 @@
+package googleauth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     []vaults.Entry
+		fails    bool
+	}{
+		{
+			"opens multiple batches",
+			"testdata/googleauth-test.txt",
+			[]vaults.Entry{
+				{Secret: "BIS22DXNONV3JPIRGF6BQMT27GLXZGJTQAIEMMGZKWYH7FJRVDSQ", Issuer: "otp.provider.dev", Label: "demo1", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Secret: "LS6OKJ4XGSREK73DPR4ACOGFYU5EBQXMNXQVSIDKXDLDZVDBMTKQ", Issuer: "otp.provider.dev", Label: "demo2", Type: "TOTP", Algorithm: "SHA256", Digits: 8, Period: 30},
+				{Secret: "YPGQF3WUM4P6LSP7J5PUM42J63KCTHYKPD2GEX2EH4DQ7452EAOA", Issuer: "otp.provider.dev", Label: "demo3", Type: "HOTP", Algorithm: "SHA1", Digits: 6, Counter: 7},
+			},
+			false,
+		},
+		{
+			"opens incomplete export",
+			"testdata/googleauth-incomplete.txt",
+			[]vaults.Entry{
+				{Secret: "BIS22DXNONV3JPIRGF6BQMT27GLXZGJTQAIEMMGZKWYH7FJRVDSQ", Issuer: "otp.provider.dev", Label: "demo1", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30},
+				{Secret: "LS6OKJ4XGSREK73DPR4ACOGFYU5EBQXMNXQVSIDKXDLDZVDBMTKQ", Issuer: "otp.provider.dev", Label: "demo2", Type: "TOTP", Algorithm: "SHA256", Digits: 8, Period: 30},
+			},
+			false,
+		},
+		{"fails: no migration data", "../otpauth/testdata/otpauth-test.txt", nil, true},
+		{"fails: missing file", "testdata/nosuchfile.txt", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Open(tt.filename, nil)
+			if tt.fails {
+				if err == nil {
+					t.Fatal("Open() expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if entries := v.Entries(); !reflect.DeepEqual(entries, tt.want) {
+				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		fails    bool
+	}{
+		{"plain", "testdata/googleauth-test.txt", false},
+		{"fails: missing file", "testdata/nosuchfile.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got {
+				t.Errorf("IsEncrypted() = %v, want false", got)
+			}
+		})
+	}
+}
@@ -1,27 +1,49 @@
 package keepass
 
 import (
+	"bytes"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
 )
 
 const vaultType = vaults.KEEPASS
 
 var _ vaults.Vault = &keepass{}
 
-type keepass struct{ entries []gokeepasslib.Entry }
+type (
+	keepass struct{ entries []entry }
+
+	// entry is a database entry and the names of its group and all parent
+	// groups, starting with the root group.
+	entry struct {
+		gokeepasslib.Entry
+		path []string
+	}
+)
 
 func Open(filename string, pass []byte) (vaults.Vault, error) {
-	v := keepass{entries: make([]gokeepasslib.Entry, 0)}
+	return OpenWithKeyFile(filename, pass, "")
+}
+
+// OpenWithKeyFile opens a database which is protected by a key file, with
+// or without an additional password.
+func OpenWithKeyFile(filename string, pass []byte, keyfile string) (vaults.Vault, error) {
+	v := keepass{entries: make([]entry, 0)}
 	db := gokeepasslib.NewDatabase()
-	db.Credentials = gokeepasslib.NewPasswordCredentials(string(pass))
+
+	var err error
+	if db.Credentials, err = credentials(pass, keyfile); err != nil {
+		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	}
 
 	f, err := os.Open(filename)
 	if err != nil {
@@ -41,38 +63,39 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 		return nil, fmt.Errorf("%s: no content", vaultType)
 	}
 
-	v.entries = append(v.entries, parseGroups(db.Content.Root.Groups)...)
+	v.entries = append(v.entries, parseGroups(db.Content.Root.Groups, nil)...)
 
 	return v, nil
 }
 
+// IsEncrypted reports whether the given file is encrypted,
+// which is always the case for KDBX files.
+func IsEncrypted(filename string) (bool, error) {
+	if _, err := os.Stat(filename); err != nil {
+		return false, fmt.Errorf("%s: %s", vaultType, err)
+	}
+	return true, nil
+}
+
 func (v keepass) Entries() []vaults.Entry {
 	entries := make([]vaults.Entry, 0)
 	for _, e := range v.entries {
 		issuer := e.GetTitle()
 
-		v := e.GetContent("otp")
-		if v == "" {
+		entry, ok, err := parseOTP(e.Entry)
+		if err != nil {
+			log.Printf("%q: %s", issuer, err)
 			continue
 		}
 
-		otp, err := url.Parse(v)
-		if err != nil {
-			log.Printf("%q: %s", issuer, err)
+		if !ok {
 			continue
 		}
 
-		period, _ := strconv.Atoi(otp.Query().Get("period"))
-		digits, _ := strconv.Atoi(otp.Query().Get("digits"))
-
-		entry := vaults.Entry{
-			Secret:    otp.Query().Get("secret"),
-			Issuer:    issuer,
-			Label:     e.GetContent("UserName"),
-			Digits:    digits,
-			Type:      strings.ToUpper(otp.Host),
-			Algorithm: otp.Query().Get("algorithm"),
-			Period:    period,
+		entry.Issuer = issuer
+		entry.Label = e.GetContent("UserName")
+		if tag := e.tag(); tag != "" {
+			entry.Tags = []string{tag}
 		}
 
 		if err := entry.SanitizeAndValidate(); err == nil {
@@ -83,11 +106,196 @@ func (v keepass) Entries() []vaults.Entry {
 	return entries
 }
 
-func parseGroups(groups []gokeepasslib.Group) []gokeepasslib.Entry {
-	entries := make([]gokeepasslib.Entry, 0)
+// Export returns the entries as KDBX 4 database, protected by the password.
+// The otpauth URI of an entry is stored in the "otp" field like KeePassXC
+// does, the first tag is used as group path below the root group.
+func Export(entries []vaults.Entry, pass []byte) ([]byte, error) {
+	if len(pass) == 0 {
+		return nil, fmt.Errorf("%s: missing password", vaultType)
+	}
+
+	db := gokeepasslib.NewDatabase(gokeepasslib.WithDatabaseKDBXVersion4())
+	db.Credentials = gokeepasslib.NewPasswordCredentials(string(pass))
+
+	root := gokeepasslib.NewGroup()
+	root.Name = "Root"
+
+	for _, e := range entries {
+		if err := e.SanitizeAndValidate(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %s", vaultType, e.QualifiedName(), err)
+		}
+
+		entry := gokeepasslib.NewEntry()
+		entry.Values = append(entry.Values,
+			value("Title", e.Title(), false),
+			value("UserName", e.Label, false),
+			value("otp", e.URI(), true),
+		)
+
+		group := &root
+		if len(e.Tags) > 0 {
+			for _, name := range strings.Split(e.Tags[0], "/") {
+				group = subGroup(group, name)
+			}
+		}
+		group.Entries = append(group.Entries, entry)
+	}
+
+	db.Content.Root.Groups = []gokeepasslib.Group{root}
+	if err := db.LockProtectedEntries(); err != nil {
+		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	var buf bytes.Buffer
+	if err := gokeepasslib.NewEncoder(&buf).Encode(db); err != nil {
+		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Returns the child group with the given name, which is created if missing.
+func subGroup(parent *gokeepasslib.Group, name string) *gokeepasslib.Group {
+	for i := range parent.Groups {
+		if parent.Groups[i].Name == name {
+			return &parent.Groups[i]
+		}
+	}
+
+	g := gokeepasslib.NewGroup()
+	g.Name = name
+	parent.Groups = append(parent.Groups, g)
+
+	return &parent.Groups[len(parent.Groups)-1]
+}
+
+func value(key, content string, protected bool) gokeepasslib.ValueData {
+	v := gokeepasslib.V{Content: content}
+	if protected {
+		v.Protected = w.NewBoolWrapper(true)
+	}
+	return gokeepasslib.ValueData{Key: key, Value: v}
+}
+
+// Returns the group path without the root group, i.e. "Work/Mail".
+func (e entry) tag() string {
+	if len(e.path) < 2 {
+		return ""
+	}
+	return strings.Join(e.path[1:], "/")
+}
+
+// Flattens the group tree, keeping the group path of each entry.
+func parseGroups(groups []gokeepasslib.Group, parent []string) []entry {
+	entries := make([]entry, 0)
 	for _, group := range groups {
-		entries = append(entries, group.Entries...)
-		entries = append(entries, parseGroups(group.Groups)...)
+		path := append(slices.Clone(parent), group.Name)
+		for _, e := range group.Entries {
+			entries = append(entries, entry{e, path})
+		}
+		entries = append(entries, parseGroups(group.Groups, path)...)
 	}
 	return entries
 }
+
+// Returns the composite key for the given password and key file.
+// A key file without a password is valid, a password alone as well.
+func credentials(pass []byte, keyfile string) (*gokeepasslib.DBCredentials, error) {
+	switch {
+	case keyfile == "":
+		return gokeepasslib.NewPasswordCredentials(string(pass)), nil
+	case len(pass) == 0:
+		return gokeepasslib.NewKeyCredentials(keyfile)
+	default:
+		return gokeepasslib.NewPasswordAndKeyCredentials(string(pass), keyfile)
+	}
+}
+
+// Reads the OTP settings of an entry. They are stored either in the "otp"
+// field as otpauth URI (KeePassXC, KeePassDX) or KeeOtp string, or in the
+// legacy "TOTP Seed" and "TOTP Settings" fields. Returns false if the
+// entry has no OTP settings at all.
+func parseOTP(e gokeepasslib.Entry) (vaults.Entry, bool, error) {
+	if v := strings.TrimSpace(e.GetContent("otp")); v != "" {
+		if strings.HasPrefix(v, "otpauth://") {
+			entry, err := vaults.ParseURI(v)
+			return entry, true, err
+		}
+
+		entry, err := parseKeeOtp(v)
+		return entry, true, err
+	}
+
+	if seed := strings.TrimSpace(e.GetContent("TOTP Seed")); seed != "" {
+		entry, err := parseLegacy(seed, e.GetContent("TOTP Settings"))
+		return entry, true, err
+	}
+
+	return vaults.Entry{}, false, nil
+}
+
+// Parses the KeeOtp format, i.e. "key=SECRET&step=30&size=6&type=Totp".
+func parseKeeOtp(s string) (vaults.Entry, error) {
+	q, err := url.ParseQuery(s)
+	if err != nil {
+		return vaults.Entry{}, err
+	}
+
+	if q.Get("key") == "" {
+		return vaults.Entry{}, fmt.Errorf("unknown otp format")
+	}
+
+	if enc := q.Get("encoding"); enc != "" && !strings.EqualFold(enc, "base32") {
+		return vaults.Entry{}, fmt.Errorf("unsupported key encoding %q", enc)
+	}
+
+	step, _ := strconv.Atoi(q.Get("step"))
+	size, _ := strconv.Atoi(q.Get("size"))
+	counter, _ := strconv.Atoi(q.Get("counter"))
+
+	typ := strings.ToUpper(q.Get("type"))
+	if typ == "" {
+		typ = vaults.TOTP
+	}
+
+	return vaults.Entry{
+		Secret:    vaults.NormalizeSecret(q.Get("key")),
+		Type:      typ,
+		Algorithm: q.Get("otpHashMode"),
+		Digits:    size,
+		Period:    step,
+		Counter:   counter,
+	}, nil
+}
+
+// Parses the legacy fields, where settings are "period;digits", optionally
+// followed by a time server URL. Steam entries use "S" as digits. Seeds are
+// often stored in lowercase or split by spaces.
+func parseLegacy(seed, settings string) (vaults.Entry, error) {
+	entry := vaults.Entry{Secret: vaults.NormalizeSecret(seed), Type: vaults.TOTP}
+	if settings == "" {
+		return entry, nil
+	}
+
+	parts := strings.Split(settings, ";")
+	if len(parts) < 2 {
+		return entry, fmt.Errorf("invalid totp settings %q", settings)
+	}
+
+	period, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return entry, fmt.Errorf("invalid totp period %q", parts[0])
+	}
+	entry.Period = period
+
+	switch digits := strings.TrimSpace(parts[1]); digits {
+	case "S":
+		entry.Type = vaults.STEAM
+	default:
+		if entry.Digits, err = strconv.Atoi(digits); err != nil {
+			return entry, fmt.Errorf("invalid totp digits %q", digits)
+		}
+	}
+
+	return entry, nil
+}
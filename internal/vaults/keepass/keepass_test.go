@@ -2,6 +2,8 @@ package keepass
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -18,15 +20,21 @@ func TestOpen(t *testing.T) {
 		name     string
 		filename string
 		password string
+		keyfile  string
 		fails    bool
 	}{
-		{"decrypts", "testdata/keepass-test.kdbx", "andcli-test", false},
-		{"fails: wrong password", "testdata/keepass-test.kdbx", "", true},
+		{"decrypts", "testdata/keepass-test.kdbx", "andcli-test", "", false},
+		{"decrypts with key file", "testdata/keepass-keyfile.kdbx", "andcli-test", "testdata/keepass-test.keyx", false},
+		{"decrypts with key file only", "testdata/keepass-keyonly.kdbx", "", "testdata/keepass-test.keyx", false},
+		{"fails: wrong password", "testdata/keepass-test.kdbx", "", "", true},
+		{"fails: missing key file", "testdata/keepass-keyfile.kdbx", "andcli-test", "", true},
+		{"fails: wrong key file", "testdata/keepass-keyfile.kdbx", "andcli-test", "testdata/keepass-test.kdbx", true},
+		{"fails: key file not found", "testdata/keepass-keyfile.kdbx", "andcli-test", "testdata/nosuchfile.keyx", true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			v, err := Open(tt.filename, []byte(tt.password))
+			v, err := OpenWithKeyFile(tt.filename, []byte(tt.password), tt.keyfile)
 			if tt.fails {
 				if err == nil {
 					t.Fatal("Open() expected error, got nil")
@@ -61,38 +69,103 @@ func TestEntries(t *testing.T) {
 				{Values: []gokeepasslib.ValueData{
 					{Key: "Title", Value: gokeepasslib.V{Content: "iss-1"}},
 					{Key: "UserName", Value: gokeepasslib.V{Content: "demo1"}},
-					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/otp.provider.dev%3Ademo1?secret=secret&period=30&digits=6&issuer=otp.provider.dev&algorithm=SHA1"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/otp.provider.dev%3Ademo1?secret=JBSWY3DP&period=30&digits=6&issuer=otp.provider.dev&algorithm=SHA1"}},
 				}},
 				{Values: []gokeepasslib.ValueData{
 					{Key: "Title", Value: gokeepasslib.V{Content: "iss-2"}},
 					{Key: "UserName", Value: gokeepasslib.V{Content: "demo2"}},
-					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://hotp/otp.provider.dev%3Ademo2?secret=secret&digits=6&issuer=otp.provider.dev&algorithm=SHA1"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://hotp/otp.provider.dev%3Ademo2?secret=JBSWY3DP&counter=3&digits=6&issuer=otp.provider.dev&algorithm=SHA1"}},
 				}},
 				{Values: []gokeepasslib.ValueData{
 					{Key: "Title", Value: gokeepasslib.V{Content: "iss-3"}},
 					{Key: "UserName", Value: gokeepasslib.V{Content: "demo3"}},
-					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/otp.provider.dev%3Ademo3?secret=secret&period=20&issuer=otp.provider.dev&algorithm=SHA1"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/otp.provider.dev%3Ademo3?secret=JBSWY3DP&period=20&issuer=otp.provider.dev&algorithm=SHA1"}},
 				}},
 				{Values: []gokeepasslib.ValueData{
 					{Key: "Title", Value: gokeepasslib.V{Content: "iss-4"}},
 					{Key: "UserName", Value: gokeepasslib.V{Content: "demo4"}},
-					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/otp.provider.dev%3Ademo1?secret=secret&digits=4&issuer=otp.provider.dev&algorithm=SHA256"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/otp.provider.dev%3Ademo1?secret=JBSWY3DP&digits=4&issuer=otp.provider.dev&algorithm=SHA256"}},
 				}},
 				{Values: []gokeepasslib.ValueData{
 					{Key: "Title", Value: gokeepasslib.V{Content: "iss-5"}},
 				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-6"}},
+					{Key: "UserName", Value: gokeepasslib.V{Content: "demo6"}},
+					{Key: "TOTP Seed", Value: gokeepasslib.V{Content: "JBSWY3DP"}},
+					{Key: "TOTP Settings", Value: gokeepasslib.V{Content: "60;8"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-7"}},
+					{Key: "UserName", Value: gokeepasslib.V{Content: "demo7"}},
+					{Key: "TOTP Seed", Value: gokeepasslib.V{Content: "JBSWY3DP"}},
+					{Key: "TOTP Settings", Value: gokeepasslib.V{Content: "30;S;https://time.example.com"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-8"}},
+					{Key: "UserName", Value: gokeepasslib.V{Content: "demo8"}},
+					{Key: "TOTP Seed", Value: gokeepasslib.V{Content: "JBSWY3DP"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-9"}},
+					{Key: "UserName", Value: gokeepasslib.V{Content: "demo9"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "key=JBSWY3DP&step=20&size=8&otpHashMode=Sha256"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-10"}},
+					{Key: "UserName", Value: gokeepasslib.V{Content: "demo10"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "key=JBSWY3DP&type=Hotp&counter=5"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-11"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "key=secret&encoding=hex"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-12"}},
+					{Key: "TOTP Seed", Value: gokeepasslib.V{Content: "JBSWY3DP"}},
+					{Key: "TOTP Settings", Value: gokeepasslib.V{Content: "invalid"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-13"}},
+					{Key: "TOTP Seed", Value: gokeepasslib.V{Content: "jbsw y3dp ehpk 3pxp"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-14"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "key=jbsw+y3dp%20ehpk+3pxp&size=6"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-15"}},
+					{Key: "TOTP Seed", Value: gokeepasslib.V{Content: "not base32!"}},
+				}},
+				{Values: []gokeepasslib.ValueData{
+					{Key: "Title", Value: gokeepasslib.V{Content: "iss-16"}},
+					{Key: "otp", Value: gokeepasslib.V{Content: "key=not+base32!"}},
+				}},
 			},
 			[]vaults.Entry{
-				{Issuer: "iss-1", Label: "demo1", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 30},
-				{Issuer: "iss-3", Label: "demo3", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 20},
-				{Issuer: "iss-4", Label: "demo4", Digits: 4, Secret: "secret", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-1", Label: "demo1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-2", Label: "demo2", Digits: 6, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+				{Issuer: "iss-3", Label: "demo3", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 20},
+				{Issuer: "iss-4", Label: "demo4", Digits: 4, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-6", Label: "demo6", Digits: 8, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 60},
+				{Issuer: "iss-7", Label: "demo7", Digits: 5, Secret: "JBSWY3DP", Type: "STEAM", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-8", Label: "demo8", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-9", Label: "demo9", Digits: 8, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "Sha256", Period: 20},
+				{Issuer: "iss-10", Label: "demo10", Digits: 6, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 5},
+				{Issuer: "iss-13", Digits: 6, Secret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-14", Digits: 6, Secret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			entries := (&keepass{tt.input}).Entries()
+			input := make([]entry, 0, len(tt.input))
+			for _, e := range tt.input {
+				input = append(input, entry{Entry: e})
+			}
+
+			entries := (&keepass{input}).Entries()
 			if !reflect.DeepEqual(entries, tt.want) {
 				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
 			}
@@ -104,7 +177,7 @@ func Test_parseGroups(t *testing.T) {
 	tests := []struct {
 		name   string
 		groups []gokeepasslib.Group
-		want   []gokeepasslib.Entry
+		want   []entry
 	}{
 		{
 			"recursive subgroups",
@@ -122,22 +195,81 @@ func Test_parseGroups(t *testing.T) {
 					}},
 				}},
 			},
-			[]gokeepasslib.Entry{
-				{IconID: 1},
-				{IconID: 2},
-				{IconID: 3},
-				{IconID: 4},
-				{IconID: 5},
+			[]entry{
+				{gokeepasslib.Entry{IconID: 1}, []string{"g1"}},
+				{gokeepasslib.Entry{IconID: 2}, []string{"g2", "g2-1"}},
+				{gokeepasslib.Entry{IconID: 3}, []string{"g2", "g2-2"}},
+				{gokeepasslib.Entry{IconID: 4}, []string{"g2", "g2-2"}},
+				{gokeepasslib.Entry{IconID: 5}, []string{"g3", "g3-1", "g3-1-2"}},
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseGroups(tt.groups)
+			got := parseGroups(tt.groups, nil)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("parseGroups() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestEntries_tags(t *testing.T) {
+	otp := gokeepasslib.Entry{Values: []gokeepasslib.ValueData{
+		{Key: "otp", Value: gokeepasslib.V{Content: "otpauth://totp/demo?secret=JBSWY3DP"}},
+	}}
+
+	tests := []struct {
+		name string
+		path []string
+		want []string
+	}{
+		{"root group", []string{"Root"}, nil},
+		{"subgroup", []string{"Root", "Work"}, []string{"Work"}},
+		{"nested subgroup", []string{"Root", "Work", "Mail"}, []string{"Work/Mail"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := (&keepass{[]entry{{otp, tt.path}}}).Entries()
+			if len(entries) != 1 {
+				t.Fatalf("Entries(): expected len to be 1, have %d", len(entries))
+			}
+			if !reflect.DeepEqual(entries[0].Tags, tt.want) {
+				t.Errorf("Entries(): tags = %v, want %v", entries[0].Tags, tt.want)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	entries := []vaults.Entry{
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "Counter", Type: "HOTP", Algorithm: "SHA256", Digits: 8, Counter: 3},
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "GitHub", Label: "alice", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30, Tags: []string{"Work/Dev"}},
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "GitLab", Label: "bob", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30, Tags: []string{"Work/Dev"}},
+	}
+
+	b, err := Export(entries, []byte("andcli-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "export.kdbx")
+	if err := os.WriteFile(filename, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Open(filename, []byte("andcli-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := v.Entries(); !reflect.DeepEqual(got, entries) {
+		t.Fatalf("Export(): want %#v\nhave %#v", entries, got)
+	}
+
+	if _, err := Export(entries, nil); err == nil {
+		t.Fatal("Export() expected error for a missing password, got none")
+	}
+}
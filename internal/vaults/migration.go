@@ -0,0 +1,183 @@
+package vaults
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Migration is a single decoded Google Authenticator export batch.
+type Migration struct {
+	Entries    []Entry
+	BatchSize  int
+	BatchIndex int
+	BatchID    int
+}
+
+var errMalformedPayload = errors.New("malformed migration payload")
+
+// protobuf wire types used by the migration payload.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// ParseMigrationURI decodes an otpauth-migration:// URI as exported by
+// Google Authenticator. The data parameter holds a base64 encoded protobuf
+// MigrationPayload. The entries are not validated.
+func ParseMigrationURI(s string) (*Migration, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "otpauth-migration" {
+		return nil, fmt.Errorf("invalid scheme %q, want otpauth-migration", u.Scheme)
+	}
+
+	// unescaped plus signs are turned into spaces by the query parser.
+	data := strings.ReplaceAll(u.Query().Get("data"), " ", "+")
+	if data == "" {
+		return nil, errors.New("missing data parameter")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		b, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return decodeMigration(b)
+}
+
+// Decodes a MigrationPayload message:
+//
+//	1: repeated OtpParameters, 2: version, 3: batch_size,
+//	4: batch_index, 5: batch_id
+func decodeMigration(b []byte) (*Migration, error) {
+	m := new(Migration)
+
+	err := decodeMessage(b, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			e, err := decodeOtpParameters(data)
+			if err != nil {
+				return err
+			}
+			m.Entries = append(m.Entries, e)
+		case 3:
+			m.BatchSize = int(v)
+		case 4:
+			m.BatchIndex = int(v)
+		case 5:
+			m.BatchID = int(int32(v))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Decodes an OtpParameters message:
+//
+//	1: secret, 2: name, 3: issuer, 4: algorithm, 5: digits,
+//	6: type, 7: counter
+func decodeOtpParameters(b []byte) (Entry, error) {
+	var e Entry
+
+	err := decodeMessage(b, func(num int, v uint64, data []byte) error {
+		switch num {
+		case 1:
+			e.Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(data)
+		case 2:
+			e.Label = string(data)
+		case 3:
+			e.Issuer = string(data)
+		case 4:
+			e.Algorithm = map[uint64]string{1: "SHA1", 2: "SHA256", 3: "SHA512", 4: "MD5"}[v]
+		case 5:
+			e.Digits = map[uint64]int{1: 6, 2: 8}[v]
+		case 6:
+			e.Type = map[uint64]string{1: HOTP, 2: TOTP}[v]
+		case 7:
+			e.Counter = int(v)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	// the name is usually "issuer:account"
+	if parts := strings.SplitN(e.Label, ":", 2); len(parts) == 2 {
+		if e.Issuer == "" {
+			e.Issuer = strings.TrimSpace(parts[0])
+		}
+		e.Label = strings.TrimSpace(parts[1])
+	}
+
+	return e, nil
+}
+
+// Walks over all fields of a protobuf message and calls fn for each field
+// with either the varint value or the length delimited data.
+func decodeMessage(b []byte, fn func(num int, v uint64, data []byte) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errMalformedPayload
+		}
+		b = b[n:]
+
+		var (
+			v    uint64
+			data []byte
+		)
+
+		switch key & 0x7 {
+		case wireVarint:
+			v, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errMalformedPayload
+			}
+			b = b[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errMalformedPayload
+			}
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		case wireI64:
+			if len(b) < 8 {
+				return errMalformedPayload
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireI32:
+			if len(b) < 4 {
+				return errMalformedPayload
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return errMalformedPayload
+		}
+
+		if err := fn(int(key>>3), v, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
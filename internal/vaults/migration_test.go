@@ -0,0 +1,61 @@
+package vaults
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMigrationURI(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		want  *Migration
+		fails bool
+	}{
+		{
+			"totp with batch info",
+			"otpauth-migration://offline?data=Ch0KCkhlbGxvId6tvu8SCUFDTUU6am9obiADKAIwAhABGAMgAij7%2F%2F%2F%2FDw%3D%3D",
+			&Migration{
+				Entries: []Entry{
+					{Secret: "JBSWY3DPEHPK3PXP", Issuer: "ACME", Label: "john", Type: TOTP, Algorithm: "SHA512", Digits: 8},
+				},
+				BatchSize:  3,
+				BatchIndex: 2,
+				BatchID:    -5,
+			},
+			false,
+		},
+		{
+			"hotp, skips unknown fields",
+			"otpauth-migration://offline?data=Ch8KCkhlbGxvId6tvu8SBWFsaWNlGgRDb3JwMAE4KngB",
+			&Migration{
+				Entries: []Entry{
+					{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Corp", Label: "alice", Type: HOTP, Counter: 42},
+				},
+			},
+			false,
+		},
+		{
+			"unescaped plus sign",
+			"otpauth-migration://offline?data=CggKAwA+EBIBeA==",
+			&Migration{Entries: []Entry{{Secret: "AA7BA", Label: "x"}}},
+			false,
+		},
+		{"fails: scheme", "otpauth://totp/alice?secret=SECRET", nil, true},
+		{"fails: missing data", "otpauth-migration://offline", nil, true},
+		{"fails: base64", "otpauth-migration://offline?data=%%%", nil, true},
+		{"fails: truncated", "otpauth-migration://offline?data=Ch0KCkhl", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMigrationURI(tt.uri)
+			if (err != nil) != tt.fails {
+				t.Fatalf("ParseMigrationURI() error = %v, wantErr %v", err, tt.fails)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMigrationURI() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
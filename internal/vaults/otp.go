@@ -0,0 +1,216 @@
+package vaults
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xlzd/gotp"
+)
+
+// steamAlphabet is the character set used for Steam Guard codes.
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+// yandexKeyLength is the length of the usable part of a Yandex secret.
+// Anything after that is a checksum.
+const yandexKeyLength = 16
+
+// Returns a generated Steam Guard code and expiration time for the current entry.
+func (e Entry) GenerateSteam() (string, int64) {
+	counter, exp := e.timeStep()
+	return e.steamAt(counter), exp
+}
+
+// Returns a generated Yandex Key code and expiration time for the current entry.
+func (e Entry) GenerateYandex() (string, int64) {
+	counter, exp := e.timeStep()
+	return e.yandexAt(counter), exp
+}
+
+// Returns a generated Mobile-OTP code and expiration time for the current entry.
+func (e Entry) GenerateMOTP() (string, int64) {
+	counter, exp := e.timeStep()
+	return e.motpAt(counter), exp
+}
+
+// Returns the token following the current one, which is the token of the
+// next time step or of the next counter value.
+func (e Entry) GenerateNext() string {
+	return e.nextAt(time.Now().Unix())
+}
+
+// Returns the token following the one valid at the unix time now.
+func (e Entry) nextAt(now int64) string {
+	if e.IsCounterBased() {
+		return gotp.NewHOTP(e.Secret, e.Digits, e.hasher()).At(e.Counter + 1)
+	}
+
+	return e.tokenAt(now/int64(e.Period) + 1)
+}
+
+// Returns the token of a time based entry for the given time step.
+func (e Entry) tokenAt(counter int64) string {
+	switch strings.ToUpper(e.Type) {
+	case STEAM:
+		return e.steamAt(counter)
+	case YANDEX:
+		return e.yandexAt(counter)
+	case MOTP:
+		return e.motpAt(counter)
+	default:
+		return gotp.NewTOTP(e.Secret, e.Digits, e.Period, e.hasher()).At(counter * int64(e.Period))
+	}
+}
+
+// Returns the current time step and its expiration time.
+func (e Entry) timeStep() (int64, int64) {
+	counter := time.Now().Unix() / int64(e.Period)
+	return counter, (counter + 1) * int64(e.Period)
+}
+
+// Returns the Steam Guard code for the given counter value. Steam uses the
+// regular HOTP truncation, but maps the result onto its own alphabet.
+func (e Entry) steamAt(counter int64) string {
+	v, err := e.truncate(counter)
+	if err != nil {
+		return ""
+	}
+
+	code := make([]byte, e.Digits)
+	for i := range code {
+		code[i] = steamAlphabet[v%uint32(len(steamAlphabet))]
+		v /= uint32(len(steamAlphabet))
+	}
+
+	return string(code)
+}
+
+// Returns the Yandex Key code for the given counter value. The HMAC key is
+// derived from the PIN and the secret, the result is mapped onto latin
+// lowercase letters.
+func (e Entry) yandexAt(counter int64) string {
+	secret, err := decodeSecret(e.Secret)
+	if err != nil {
+		return ""
+	}
+
+	if len(secret) > yandexKeyLength {
+		secret = secret[:yandexKeyLength]
+	}
+
+	key := sha256.Sum256(append([]byte(e.Pin), secret...))
+	k := key[:]
+	if k[0] == 0 {
+		k = k[1:]
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(sha256.New, k)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint64(sum[offset:offset+8]) & 0x7fffffffffffffff
+
+	code := make([]byte, e.Digits)
+	for i := len(code) - 1; i >= 0; i-- {
+		code[i] = byte('a' + v%26)
+		v /= 26
+	}
+
+	return string(code)
+}
+
+// Returns the Mobile-OTP code for the given counter value: the first digits
+// of the MD5 hash over counter, hex secret and PIN.
+func (e Entry) motpAt(counter int64) string {
+	secret, err := e.motpSecret()
+	if err != nil {
+		return ""
+	}
+
+	sum := md5.Sum(fmt.Appendf(nil, "%d%s%s", counter, secret, e.Pin))
+	code := hex.EncodeToString(sum[:])
+
+	return code[:min(e.Digits, len(code))]
+}
+
+// Returns the Mobile-OTP secret as it is hashed. mOTP hashes the hex string
+// as entered, so hex secrets keep their case and length. Vaults that store
+// the raw bytes as base32, like Aegis, are converted to lowercase hex.
+func (e Entry) motpSecret() (string, error) {
+	s := strings.Join(strings.Fields(e.Secret), "")
+	if !e.HexSecret {
+		b, err := decodeSecret(s)
+		if err != nil || len(b) == 0 {
+			return "", errors.New("mOTP secret is no valid base32")
+		}
+		return hex.EncodeToString(b), nil
+	}
+
+	if s == "" || strings.Trim(s, "0123456789abcdefABCDEF") != "" {
+		return "", errors.New("mOTP secret is no valid hex")
+	}
+
+	return s, nil
+}
+
+// Base32Secret returns the secret of the entry as base32. The hex secrets
+// of mOTP entries are converted, they need an even length.
+func (e Entry) Base32Secret() (string, error) {
+	if !e.HexSecret {
+		return e.Secret, nil
+	}
+
+	b, err := hex.DecodeString(strings.Join(strings.Fields(e.Secret), ""))
+	if err != nil || len(b) == 0 {
+		return "", ErrInvalidSecret
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// Returns the dynamically truncated HMAC value (RFC 4226, 5.3) of the entry
+// secret for the given counter value.
+func (e Entry) truncate(counter int64) (uint32, error) {
+	key, err := decodeSecret(e.Secret)
+	if err != nil {
+		return 0, err
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(e.hasher().Digest, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	return binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff, nil
+}
+
+// NormalizeSecret returns the base32 secret s without whitespace and
+// padding, in uppercase, as expected by the token generators.
+func NormalizeSecret(s string) string {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	return strings.TrimRight(s, "=")
+}
+
+// Decodes a base32 secret, ignoring whitespace, case and padding. Like
+// gotp, it pads the secret and fails on lengths no encoder produces.
+func decodeSecret(s string) ([]byte, error) {
+	s = NormalizeSecret(s)
+	if n := len(s) % 8; n != 0 {
+		s += strings.Repeat("=", 8-n)
+	}
+	return base32.StdEncoding.DecodeString(s)
+}
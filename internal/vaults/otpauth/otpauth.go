@@ -0,0 +1,131 @@
+package otpauth
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/gopenpgp/v3/crypto"
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+const vaultType = vaults.OTPAUTH
+
+var _ vaults.Vault = &otpauth{}
+
+var (
+	ageHeader      = []byte("age-encryption.org/")
+	ageArmorHeader = []byte(armor.Header)
+	pgpArmorHeader = []byte("-----BEGIN PGP MESSAGE-----")
+)
+
+// otpauth is a plain text list of otpauth:// URIs, one per line.
+type otpauth struct{ uris []vaults.Line }
+
+func Open(filename string, pass []byte) (vaults.Vault, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	switch {
+	case isAge(b):
+		b, err = decryptAge(b, pass)
+	case isPGP(b):
+		b, err = decryptPGP(b, pass)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	return &otpauth{uris: vaults.Lines(b)}, nil
+}
+
+// IsEncrypted reports whether the given file is age or PGP encrypted.
+func IsEncrypted(filename string) (bool, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", vaultType, err)
+	}
+	return isAge(b) || isPGP(b), nil
+}
+
+func (v otpauth) Entries() []vaults.Entry {
+	entries := make([]vaults.Entry, 0)
+
+	for _, line := range v.uris {
+		entry, err := vaults.ParseURI(line.Text)
+		if err != nil {
+			log.Printf("line %d: %s", line.Number, err)
+			continue
+		}
+
+		if err := entry.SanitizeAndValidate(); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// Export returns the entries as plain otpauth:// URI list. The list is
+// not encrypted, the password is ignored.
+func Export(entries []vaults.Entry, _ []byte) ([]byte, error) {
+	var b bytes.Buffer
+	for _, e := range entries {
+		if err := e.SanitizeAndValidate(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %s", vaultType, e.QualifiedName(), err)
+		}
+		fmt.Fprintln(&b, e.URI())
+	}
+	return b.Bytes(), nil
+}
+
+func isAge(b []byte) bool {
+	return bytes.HasPrefix(b, ageHeader) || bytes.HasPrefix(bytes.TrimSpace(b), ageArmorHeader)
+}
+
+// PGP messages are either armored or binary.
+func isPGP(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), pgpArmorHeader) || vaults.IsPGP(b)
+}
+
+// Decrypts a passphrase protected age file, armored or binary.
+func decryptAge(b, pass []byte) ([]byte, error) {
+	id, err := age.NewScryptIdentity(string(pass))
+	if err != nil {
+		return nil, err
+	}
+
+	var src io.Reader = bytes.NewReader(b)
+	if bytes.HasPrefix(bytes.TrimSpace(b), ageArmorHeader) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(b)))
+	}
+
+	r, err := age.Decrypt(src, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+// Decrypts a symmetrically encrypted PGP message, armored or binary.
+func decryptPGP(b, pass []byte) ([]byte, error) {
+	hnd, err := crypto.PGP().Decryption().Password(pass).New()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := hnd.Decrypt(b, crypto.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Bytes(), nil
+}
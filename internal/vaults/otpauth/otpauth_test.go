@@ -0,0 +1,130 @@
+package otpauth
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tjblackheart/andcli/v2/internal/vaults"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		password string
+		fails    bool
+	}{
+		{"opens plain", "testdata/otpauth-test.txt", "", false},
+		{"decrypts age", "testdata/otpauth-test.txt.age", "andcli-test", false},
+		{"decrypts gpg", "testdata/otpauth-test.txt.gpg", "andcli-test", false},
+		{"fails: age wrong password", "testdata/otpauth-test.txt.age", "invalid", true},
+		{"fails: gpg wrong password", "testdata/otpauth-test.txt.gpg", "invalid", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := Open(tt.filename, []byte(tt.password))
+			if tt.fails {
+				if err == nil {
+					t.Fatal("Open() expected error, got nil")
+				}
+				return
+			}
+
+			entries := v.Entries()
+			if len(entries) != 3 {
+				t.Fatalf("Open() expected len to be 3, have %v", len(entries))
+			}
+
+			for i := range 3 {
+				want := fmt.Sprintf("demo%d", i+1)
+				if entries[i].Label != want {
+					t.Fatalf("Open() have %v, %s", entries[i].Label, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"plain", "testdata/otpauth-test.txt", false, false},
+		{"plain: byte order mark", "testdata/otpauth-test-bom.txt", false, false},
+		{"age", "testdata/otpauth-test.txt.age", true, false},
+		{"gpg", "testdata/otpauth-test.txt.gpg", true, false},
+		{"fails: missing file", "testdata/nosuchfile.txt", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []vaults.Entry
+	}{
+		{
+			"mitigates missing fields",
+			[]string{
+				"otpauth://totp/iss-1:demo1?secret=JBSWY3DP&digits=6",
+				"otpauth://totp/demo2?secret=JBSWY3DP&issuer=iss-2&period=20&algorithm=SHA256",
+				"otpauth://hotp/iss-3:demo3?secret=JBSWY3DP&counter=3",
+				"otpauth://totp/iss-4:demo4",
+				"invalid://totp/iss-5:demo5?secret=JBSWY3DP",
+			},
+			[]vaults.Entry{
+				{Issuer: "iss-1", Label: "demo1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-2", Label: "demo2", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256", Period: 20},
+				{Issuer: "iss-3", Label: "demo3", Digits: 6, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uris := vaults.Lines([]byte(strings.Join(tt.input, "\n")))
+			entries := (&otpauth{uris: uris}).Entries()
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	entries := []vaults.Entry{
+		{Issuer: "iss-1", Label: "demo1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+		{Issuer: "iss-3", Label: "demo3", Digits: 6, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+	}
+
+	b, err := Export(entries, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := (&otpauth{uris: vaults.Lines(b)}).Entries(); !reflect.DeepEqual(got, entries) {
+		t.Fatalf("Export(): want %#v\nhave %#v", entries, got)
+	}
+
+	if _, err := Export([]vaults.Entry{{Issuer: "invalid"}}, nil); err == nil {
+		t.Fatal("Export() expected error for an invalid entry, got none")
+	}
+}
@@ -8,9 +8,7 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net/url"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/ProtonMail/gopenpgp/v3/crypto"
@@ -21,6 +19,8 @@ const vaultType = vaults.PROTON
 
 var _ vaults.Vault = &envelope{}
 
+var errInvalidFile = errors.New("not a Proton Pass export: missing vaults")
+
 type (
 	envelope struct{ Vaults map[string]proton }
 
@@ -46,24 +46,50 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 		return nil, fmt.Errorf("%s: %s", vaultType, err)
 	}
 
-	hnd, err := crypto.PGP().Decryption().Password(pass).New()
-	if err != nil {
-		return nil, fmt.Errorf("%s: %s", vaultType, err)
+	if encrypted(b) {
+		hnd, err := crypto.PGP().Decryption().Password(pass).New()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", vaultType, err)
+		}
+
+		result, err := hnd.Decrypt(b, crypto.Armor)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", vaultType, err)
+		}
+
+		b = result.Bytes()
 	}
 
-	result, err := hnd.Decrypt(b, crypto.Armor)
-	if err != nil {
+	if err := validate(b); err != nil {
 		return nil, fmt.Errorf("%s: %s", vaultType, err)
 	}
 
 	var e envelope
-	if err := json.Unmarshal(result.Bytes(), &e); err != nil {
+	if err := json.Unmarshal(b, &e); err != nil {
 		return nil, fmt.Errorf("%s: %s", vaultType, err)
 	}
 
 	return e, nil
 }
 
+// IsEncrypted reports whether the given file is an encrypted export.
+func IsEncrypted(filename string) (bool, error) {
+	b, err := read(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	if encrypted(b) {
+		return true, nil
+	}
+
+	if err := validate(b); err != nil {
+		return false, fmt.Errorf("%s: %s", vaultType, err)
+	}
+
+	return false, nil
+}
+
 func (e envelope) Entries() []vaults.Entry {
 	entries := make([]vaults.Entry, 0)
 
@@ -75,23 +101,16 @@ func (e envelope) Entries() []vaults.Entry {
 			}
 
 			issuer := d.Metadata.Name
-			uri, err := url.Parse(d.Content.TOTPUri)
+			entry, err := vaults.ParseURI(d.Content.TOTPUri)
 			if err != nil {
 				log.Printf("%q: %s", issuer, err)
 				continue
 			}
 
-			period, _ := strconv.Atoi(uri.Query().Get("period"))
-			digits, _ := strconv.Atoi(uri.Query().Get("digits"))
-
-			entry := vaults.Entry{
-				Secret:    uri.Query().Get("secret"),
-				Issuer:    issuer,
-				Label:     d.Content.Username,
-				Digits:    digits,
-				Type:      strings.ToUpper(uri.Host),
-				Algorithm: uri.Query().Get("algorithm"),
-				Period:    period,
+			entry.Issuer = issuer
+			entry.Label = d.Content.Username
+			if v.Name != "" {
+				entry.Tags = []string{v.Name}
 			}
 
 			if err := entry.SanitizeAndValidate(); err == nil {
@@ -129,16 +148,40 @@ func read(filename string) ([]byte, error) {
 	}
 	defer r.Close()
 
-	if len(r.File) == 0 {
-		return nil, errors.New("archive has no content")
+	// read only the first file entry.
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
 	}
 
-	// read only the first entry.
-	rc, err := r.File[0].Open()
-	if err != nil {
-		return nil, err
+	return nil, errors.New("archive has no content")
+}
+
+// Unencrypted exports contain the plain JSON data.
+func encrypted(b []byte) bool {
+	return !json.Valid(b)
+}
+
+// Checks that the plain export is an object with vaults, so that any other
+// JSON file is not opened as empty vault.
+func validate(b []byte) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return errInvalidFile
+	}
+
+	if _, ok := keys["vaults"]; !ok {
+		return errInvalidFile
 	}
-	defer rc.Close()
 
-	return io.ReadAll(rc)
+	return nil
 }
@@ -19,7 +19,9 @@ func TestOpen(t *testing.T) {
 		{"decrypts text", "testdata/protonpass-test.pgp", "andcli-test", false},
 		{"decrypts zip", "testdata/protonpass-test.pgp.zip", "andcli-test", false},
 		{"decrypts hidden zip", "testdata/protonpass-test.pgp.data", "andcli-test", false},
+		{"opens plain zip", "testdata/protonpass-test.zip", "", false},
 		{"fails: wrong password", "testdata/protonpass-test.pgp", "", true},
+		{"fails: other json", "testdata/protonpass-other.json", "", true},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +44,36 @@ func TestOpen(t *testing.T) {
 				if entries[i].Label != want {
 					t.Fatalf("Open() have %v, %s", entries[i].Label, want)
 				}
+				if len(entries[i].Tags) != 1 || entries[i].Tags[0] != "Personal" {
+					t.Fatalf("Open() have tags %v, want [Personal]", entries[i].Tags)
+				}
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted text", "testdata/protonpass-test.pgp", true, false},
+		{"encrypted zip", "testdata/protonpass-test.pgp.zip", true, false},
+		{"plain zip", "testdata/protonpass-test.zip", false, false},
+		{"fails: missing file", "testdata/nosuchfile.zip", false, true},
+		{"fails: other json", "testdata/protonpass-other.json", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
 			}
 		})
 	}
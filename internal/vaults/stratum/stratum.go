@@ -1,8 +1,10 @@
 package stratum
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
 )
 
 const vaultType = vaults.STRATUM
@@ -66,6 +69,13 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 
 	v := &stratum{Authenticators: make([]entry, 0)}
 
+	if !encrypted(b) {
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, fmt.Errorf("%s: %w", vaultType, err)
+		}
+		return v, nil
+	}
+
 	switch string(b[:len(HEADER)]) {
 	case HEADER:
 		b, err := v.decrypt(b, pass)
@@ -91,6 +101,15 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 	return v, nil
 }
 
+// IsEncrypted reports whether the given file is an encrypted backup.
+func IsEncrypted(filename string) (bool, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return encrypted(b), nil
+}
+
 func (v stratum) Entries() []vaults.Entry {
 	// https://github.com/stratumauth/app/blob/master/doc/BACKUP_FORMAT.md
 	// Algorithm (applies to HOTP and TOTP): 0 = SHA-1, 1 = SHA-256, 2 = SHA-512
@@ -99,22 +118,29 @@ func (v stratum) Entries() []vaults.Entry {
 	list := make([]vaults.Entry, 0)
 	for _, e := range v.Authenticators {
 
-		alg := "SHA1"
-		switch e.Algorithm {
-		case 1:
+		alg := ""
+		switch {
+		case e.Type != 1 && e.Type != 2:
+			// defaults are set by the entry validation
+		case e.Algorithm == 1:
 			alg = "SHA256"
-		case 2:
+		case e.Algorithm == 2:
 			alg = "SHA512"
+		default:
+			alg = "SHA1"
 		}
 
 		entry := vaults.Entry{
 			Secret:    e.Secret,
+			Pin:       e.Pin,
 			Issuer:    e.Issuer,
 			Digits:    int(e.Digits),
 			Type:      e.typeToString(),
 			Algorithm: alg,
 			Period:    e.Period,
 			Label:     e.Username,
+			Counter:   e.Counter,
+			HexSecret: e.Type == 3,
 		}
 
 		if err := entry.SanitizeAndValidate(); err == nil {
@@ -144,8 +170,57 @@ func (v stratum) decrypt(b, pass []byte) ([]byte, error) {
 	return gcm.Open(nil, nonce, payload, nil)
 }
 
-func (v stratum) decryptLegacy(_, _ []byte) ([]byte, error) {
-	return nil, errors.New("decryption of stratum legacy vaults is not implemented")
+func (v stratum) decryptLegacy(b, pass []byte) ([]byte, error) {
+	offset := len(LEGACY_HEADER) + LEGACY_SALT_LENGTH + LEGACY_IV_LENGTH
+	if len(b) <= offset {
+		return nil, errors.New("invalid legacy vault file: too short")
+	}
+
+	salt := b[len(LEGACY_HEADER) : len(LEGACY_HEADER)+LEGACY_SALT_LENGTH]
+	iv := b[len(LEGACY_HEADER)+LEGACY_SALT_LENGTH : offset]
+	payload := b[offset:]
+	key := pbkdf2.Key(pass, salt, LEGACY_ITERATIONS, KEY_LENGTH, sha1.New)
+
+	if len(payload)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid legacy vault file: payload is not a multiple of the block size")
+	}
+
+	cb, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, len(payload))
+	cipher.NewCBCDecrypter(cb, iv).CryptBlocks(plain, payload)
+
+	return unpad(plain)
+}
+
+// Encrypted backups start with one of the known headers,
+// plain backups are JSON.
+func encrypted(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(HEADER)) || bytes.HasPrefix(b, []byte(LEGACY_HEADER))
+}
+
+// Removes PKCS#7 padding. A wrong password will most likely result in
+// invalid padding, so this doubles as the password check.
+func unpad(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, errors.New("decrypt: empty payload")
+	}
+
+	n := int(b[len(b)-1])
+	if n == 0 || n > aes.BlockSize || n > len(b) {
+		return nil, errors.New("decrypt: invalid padding, wrong password?")
+	}
+
+	for _, c := range b[len(b)-n:] {
+		if int(c) != n {
+			return nil, errors.New("decrypt: invalid padding, wrong password?")
+		}
+	}
+
+	return b[:len(b)-n], nil
 }
 
 func (e entry) typeToString() string {
@@ -21,7 +21,10 @@ func TestOpen(t *testing.T) {
 	}{
 		{"decrypts", "testdata/backup-andcli-test.stratum", "andcli-test", false},
 		{"fails: wrong password", "testdata/backup-andcli-test.stratum", "", true},
-		{"fails: legacy", "testdata/backup-legacy-andcli-test.stratum", "", true},
+		{"opens plain", "testdata/backup-andcli-test.json", "", false},
+		{"decrypts legacy", "testdata/backup-legacy-andcli-test.stratum", "andcli-test", false},
+		{"fails: legacy wrong password", "testdata/backup-legacy-andcli-test.stratum", "", true},
+		{"fails: legacy invalid file", "testdata/backup-legacy-invalid-file.stratum", "andcli-test", true},
 	}
 
 	for _, tt := range tests {
@@ -49,6 +52,32 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/backup-andcli-test.stratum", true, false},
+		{"encrypted legacy", "testdata/backup-legacy-andcli-test.stratum", true, false},
+		{"plain", "testdata/backup-andcli-test.json", false, false},
+		{"fails: missing file", "testdata/nosuchfile.json", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestEntries(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -58,16 +87,24 @@ func TestEntries(t *testing.T) {
 		{
 			"mitigates missing fields",
 			[]entry{
-				{Issuer: "iss-1", Digits: 6, Secret: "secret", Type: 2},
-				{Issuer: "iss-2", Digits: 4, Secret: "secret", Type: 1},
-				{Issuer: "iss-3", Digits: 0, Secret: "secret", Type: 2, Period: 20},
-				{Issuer: "iss-4", Digits: 4, Secret: "secret", Type: 2, Algorithm: 1},
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: 2},
+				{Issuer: "iss-2", Digits: 4, Secret: "JBSWY3DP", Type: 1, Counter: 3},
+				{Issuer: "iss-3", Digits: 0, Secret: "JBSWY3DP", Type: 2, Period: 20},
+				{Issuer: "iss-4", Digits: 4, Secret: "JBSWY3DP", Type: 2, Algorithm: 1},
 				{Issuer: "iss-5"},
+				{Issuer: "iss-6", Secret: "JBSWY3DP", Type: 4},
+				{Issuer: "iss-7", Secret: "JBSWY3DP", Type: 5, Pin: "1234"},
+				{Issuer: "iss-8", Secret: "3132333435363738", Type: 3, Pin: "1234"},
+				{Issuer: "iss-9", Secret: "JBSWY3DP", Type: 3},
 			},
 			[]vaults.Entry{
-				{Issuer: "iss-1", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 30},
-				{Issuer: "iss-3", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 20},
-				{Issuer: "iss-4", Digits: 4, Secret: "secret", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-2", Digits: 4, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+				{Issuer: "iss-3", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 20},
+				{Issuer: "iss-4", Digits: 4, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-6", Digits: 5, Secret: "JBSWY3DP", Type: "STEAM", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-7", Digits: 8, Secret: "JBSWY3DP", Pin: "1234", Type: "YANDEX", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-8", Digits: 6, Secret: "3132333435363738", Pin: "1234", Type: "MOTP", Algorithm: "MD5", Period: 10, HexSecret: true},
 			},
 		},
 	}
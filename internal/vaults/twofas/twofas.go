@@ -1,14 +1,19 @@
 package twofas
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/tjblackheart/andcli/v2/internal/vaults"
 	"golang.org/x/crypto/pbkdf2"
@@ -18,9 +23,18 @@ const (
 	vaultType         = vaults.TWOFAS
 	numFields     int = 3
 	authTagLength int = 16
+	saltLength    int = 256
 )
 
-var _ vaults.Vault = &twofas{}
+// header of exported files, with the values of the app version the format
+// was taken from. The app checks the password by decrypting "reference",
+// an encrypted constant, if present, and the services otherwise, so it is
+// left out instead of written empty.
+const exportHeader = `{"services":[],"groups":[],"schemaVersion":4,"appVersionCode":5000012,"appVersionName":"5.2.0","appOrigin":"android"}`
+
+var _ vaults.Writable = &twofas{}
+
+var errInvalidFile = errors.New("not a 2FAS backup: missing services")
 
 type (
 	twofas struct {
@@ -30,8 +44,13 @@ type (
 		AppVersionName    string
 		AppOrigin         string
 		ServicesEncrypted string
+		Services          []entry
+		Groups            []struct{ ID, Name string }
 		//
-		db []entry
+		db       []entry
+		filename string
+		key      []byte          // derived key, nil for plain exports
+		raw      json.RawMessage // the file, to keep unknown fields on save
 	}
 
 	entry struct {
@@ -40,6 +59,7 @@ type (
 		UpdatedAt int
 		Otp       otp
 		Order     struct{ Position int }
+		GroupID   string `json:"groupId"`
 		Icon      struct {
 			Selected string
 			Label    struct {
@@ -50,6 +70,7 @@ type (
 				Id string
 			} `json:"iconCollection"`
 		}
+		raw json.RawMessage
 	}
 
 	otp struct {
@@ -58,22 +79,23 @@ type (
 		Issuer    string
 		Digits    int
 		Period    int
+		Counter   int
 		Algorithm string
 		TokenType string `json:"tokenType"`
 		Source    string
+		raw       json.RawMessage
 	}
 )
 
 func Open(filename string, pass []byte) (vaults.Vault, error) {
-	var v twofas
-
-	b, err := os.ReadFile(filename)
+	v, err := read(filename)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	if err := json.Unmarshal(b, &v); err != nil {
-		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	if !v.encrypted() {
+		v.db = v.Services
+		return &v, nil
 	}
 
 	key, err := v.masterKeyFromPass(pass)
@@ -90,13 +112,40 @@ func Open(filename string, pass []byte) (vaults.Vault, error) {
 		return nil, fmt.Errorf("%s: %w", vaultType, err)
 	}
 
-	return v, nil
+	v.key = key
+
+	return &v, nil
+}
+
+// IsEncrypted reports whether the given file is an encrypted export.
+func IsEncrypted(filename string) (bool, error) {
+	v, err := read(filename)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", vaultType, err)
+	}
+	return v.encrypted(), nil
 }
 
 func (v twofas) Entries() []vaults.Entry {
-	entries := make([]vaults.Entry, 0)
+	entries, _ := v.entries()
+	return entries
+}
+
+// Returns the valid entries and their index in the db.
+func (v twofas) entries() ([]vaults.Entry, []int) {
+	entries, indexes := make([]vaults.Entry, 0), make([]int, 0)
+
+	groups := make(map[string]string)
+	for _, g := range v.Groups {
+		groups[g.ID] = g.Name
+	}
+
+	for i, e := range v.db {
+		var tags []string
+		if name, ok := groups[e.GroupID]; ok {
+			tags = append(tags, name)
+		}
 
-	for _, e := range v.db {
 		label := e.Otp.Label
 		if label == "" {
 			label = e.Otp.Account
@@ -110,14 +159,279 @@ func (v twofas) Entries() []vaults.Entry {
 			Type:      strings.ToUpper(e.Otp.TokenType),
 			Algorithm: e.Otp.Algorithm,
 			Period:    e.Otp.Period,
+			Counter:   e.Otp.Counter,
+			Tags:      tags,
 		}
 
 		if err := entry.SanitizeAndValidate(); err == nil {
 			entries = append(entries, entry)
+			indexes = append(indexes, i)
 		}
 	}
 
-	return entries
+	return entries, indexes
+}
+
+func (v *twofas) Add(e vaults.Entry) error {
+	var dst entry
+	if err := v.set(&dst, e); err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	v.db = append(v.db, dst)
+	return nil
+}
+
+func (v *twofas) Update(i int, e vaults.Entry) error {
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+
+	if err := v.set(&v.db[j], e); err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return nil
+}
+
+func (v *twofas) Remove(i int) error {
+	j, err := v.index(i)
+	if err != nil {
+		return err
+	}
+
+	v.db = slices.Delete(v.db, j, j+1)
+	return nil
+}
+
+// Save writes the vault back to its file. Encrypted backups are encrypted
+// with the existing key and salt, so the password stays the same.
+func (v *twofas) Save() error {
+	b, err := v.encode()
+	if err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	if err := vaults.WriteFile(v.filename, b); err != nil {
+		return fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return nil
+}
+
+// Export returns the entries as new backup file. With a password, the
+// services are encrypted with a key derived from it and a new salt,
+// otherwise it is a plain backup.
+func Export(entries []vaults.Entry, pass []byte) ([]byte, error) {
+	v := &twofas{raw: json.RawMessage(exportHeader)}
+
+	if len(pass) > 0 {
+		salt := make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("%s: %w", vaultType, err)
+		}
+
+		// encryptDB takes the salt from the encrypted services.
+		v.key = pbkdf2.Key(pass, salt, 10000, 32, sha256.New)
+		v.ServicesEncrypted = ":" + base64.StdEncoding.EncodeToString(salt) + ":"
+	}
+
+	for _, e := range entries {
+		// Add prefixes its errors with the vault type.
+		if err := v.Add(e); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.QualifiedName(), err)
+		}
+	}
+
+	b, err := v.encode()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vaultType, err)
+	}
+
+	return b, nil
+}
+
+// Returns the vault as JSON, like the app writes it.
+func (v *twofas) encode() ([]byte, error) {
+	services := make([]json.RawMessage, 0, len(v.db))
+	for _, e := range v.db {
+		services = append(services, e.raw)
+	}
+
+	groups, err := v.rawGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]any{
+		"updatedAt": time.Now().UnixMilli(),
+		"groups":    groups,
+		"services":  services,
+	}
+
+	if v.encrypted() {
+		plain, err := json.Marshal(services)
+		if err != nil {
+			return nil, err
+		}
+
+		enc, err := v.encryptDB(plain)
+		if err != nil {
+			return nil, err
+		}
+
+		fields["servicesEncrypted"], fields["services"] = enc, []entry{}
+		v.ServicesEncrypted = enc
+	}
+
+	b, err := vaults.Patch(v.raw, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	v.raw = b
+	return b, nil
+}
+
+// Returns the db index of the i-th entry of Entries().
+func (v twofas) index(i int) (int, error) {
+	_, indexes := v.entries()
+	if i < 0 || i >= len(indexes) {
+		return 0, fmt.Errorf("%s: %w", vaultType, vaults.ErrNoEntry)
+	}
+	return indexes[i], nil
+}
+
+// Applies e to the service dst. Fields unknown to andcli are kept. 2FAS
+// supports a single group per service, which is set from the first tag.
+func (v *twofas) set(dst *entry, e vaults.Entry) error {
+	if err := e.SanitizeAndValidate(); err != nil {
+		return err
+	}
+
+	if !slices.Contains([]string{vaults.TOTP, vaults.HOTP, vaults.STEAM}, e.Type) {
+		return vaults.ErrInvalidType
+	}
+
+	// Entries reads the label from "account" if "label" is empty. Only that
+	// field is changed, and "account" if it was a copy of the label.
+	label, account := e.Label, e.Label
+	if dst.Otp.raw != nil {
+		switch {
+		case dst.Otp.Label == "":
+			label = ""
+		case dst.Otp.Account != dst.Otp.Label:
+			account = dst.Otp.Account
+		}
+	}
+
+	fields := map[string]any{
+		"label":     label,
+		"account":   account,
+		"issuer":    e.Issuer,
+		"digits":    e.Digits,
+		"period":    e.Period,
+		"counter":   e.Counter,
+		"algorithm": e.Algorithm,
+		"tokenType": e.Type,
+	}
+
+	if dst.Otp.raw == nil {
+		fields["source"] = "Manual"
+	}
+
+	otpRaw, err := vaults.Patch(dst.Otp.raw, fields)
+	if err != nil {
+		return err
+	}
+
+	dst.Name, dst.Secret = e.Title(), e.Secret
+	dst.UpdatedAt = int(time.Now().UnixMilli())
+	dst.Otp = otp{
+		Label:     label,
+		Account:   account,
+		Issuer:    e.Issuer,
+		Digits:    e.Digits,
+		Period:    e.Period,
+		Counter:   e.Counter,
+		Algorithm: e.Algorithm,
+		TokenType: e.Type,
+		raw:       otpRaw,
+	}
+
+	fields = map[string]any{
+		"name":      dst.Name,
+		"secret":    dst.Secret,
+		"updatedAt": dst.UpdatedAt,
+		"otp":       otpRaw,
+		"groupId":   nil,
+	}
+
+	dst.GroupID = ""
+	if len(e.Tags) > 0 {
+		dst.GroupID = v.groupID(e.Tags[0])
+		fields["groupId"] = dst.GroupID
+	}
+
+	if dst.raw == nil {
+		dst.Order.Position = len(v.db)
+		fields["order"] = map[string]int{"position": dst.Order.Position}
+	}
+
+	raw, err := vaults.Patch(dst.raw, fields)
+	if err != nil {
+		return err
+	}
+
+	dst.raw = raw
+	return nil
+}
+
+// Returns the id of the group with the given name. A missing group is
+// created.
+func (v *twofas) groupID(name string) string {
+	for _, g := range v.Groups {
+		if strings.EqualFold(g.Name, name) {
+			return g.ID
+		}
+	}
+
+	id := vaults.NewUUID()
+	v.Groups = append(v.Groups, struct{ ID, Name string }{id, name})
+	return id
+}
+
+// Returns the groups of the file plus the groups created since opening it.
+func (v twofas) rawGroups() ([]json.RawMessage, error) {
+	var file struct{ Groups []json.RawMessage }
+	if len(v.raw) > 0 {
+		if err := json.Unmarshal(v.raw, &file); err != nil {
+			return nil, err
+		}
+	}
+
+	groups := append(make([]json.RawMessage, 0), file.Groups...)
+	for _, g := range v.Groups[len(file.Groups):] {
+		b, err := json.Marshal(map[string]any{
+			"id":         g.ID,
+			"name":       g.Name,
+			"isExpanded": true,
+			"updatedAt":  time.Now().UnixMilli(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, b)
+	}
+
+	return groups, nil
+}
+
+// Plain exports store the services directly, encrypted exports
+// leave the services list empty.
+func (v twofas) encrypted() bool {
+	return v.ServicesEncrypted != ""
 }
 
 func (v twofas) masterKeyFromPass(password []byte) ([]byte, error) {
@@ -190,3 +504,85 @@ func (v twofas) decryptDB(key []byte) ([]byte, error) {
 
 	return plain, nil
 }
+
+// Encrypts the services with the key, the salt of the file and a new nonce.
+func (v twofas) encryptDB(plain []byte) (string, error) {
+	servicesEncrypted := strings.SplitN(v.ServicesEncrypted, ":", numFields+1)
+	if len(servicesEncrypted) != numFields {
+		return "", fmt.Errorf("invalid vault file: number of fields is not %d", numFields)
+	}
+
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	// the cipher text is followed by the auth tag, like in the file.
+	b := gcm.Seal(nil, nonce, plain, nil)
+
+	return strings.Join([]string{
+		base64.StdEncoding.EncodeToString(b),
+		servicesEncrypted[1],
+		base64.StdEncoding.EncodeToString(nonce),
+	}, ":"), nil
+}
+
+// reads and parses the vault file.
+func read(filename string) (twofas, error) {
+	v := twofas{filename: filename}
+
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return v, err
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, err
+	}
+
+	// any other JSON object would be opened as empty vault.
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return v, err
+	}
+
+	_, services := keys["services"]
+	_, servicesEncrypted := keys["servicesEncrypted"]
+	if !services && !servicesEncrypted {
+		return v, errInvalidFile
+	}
+
+	v.raw = b
+
+	return v, nil
+}
+
+// keeps the raw service, to write back fields unknown to andcli.
+func (e *entry) UnmarshalJSON(b []byte) error {
+	type plain entry
+	if err := json.Unmarshal(b, (*plain)(e)); err != nil {
+		return err
+	}
+	e.raw = bytes.Clone(b)
+	return nil
+}
+
+// keeps the raw otp params, to write back fields unknown to andcli.
+func (o *otp) UnmarshalJSON(b []byte) error {
+	type plain otp
+	if err := json.Unmarshal(b, (*plain)(o)); err != nil {
+		return err
+	}
+	o.raw = bytes.Clone(b)
+	return nil
+}
@@ -1,6 +1,12 @@
 package twofas
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -15,8 +21,10 @@ func TestOpen(t *testing.T) {
 		fails    bool
 	}{
 		{"decrypts", "testdata/twofas-export-test.2fas", "andcli-test", false},
+		{"opens plain", "testdata/twofas-export-plain.2fas", "", false},
 		{"fails: wrong password", "testdata/twofas-export-test.2fas", "invalid", true},
 		{"fails: invalid file", "testdata/twofas-invalid-file.2fas", "invalid", true},
+		{"fails: other json", "testdata/twofas-other-json.2fas", "", true},
 	}
 
 	for _, tt := range tests {
@@ -41,35 +49,289 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     bool
+		fails    bool
+	}{
+		{"encrypted", "testdata/twofas-export-test.2fas", true, false},
+		{"plain", "testdata/twofas-export-plain.2fas", false, false},
+		{"fails: invalid file", "testdata/twofas-invalid-file.2fas", false, true},
+		{"fails: other json", "testdata/twofas-other-json.2fas", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsEncrypted(tt.filename)
+			if (err != nil) != tt.fails {
+				t.Fatalf("IsEncrypted() error = %v, wantErr %v", err, tt.fails)
+			}
+			if got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestEntries(t *testing.T) {
 	tests := []struct {
-		name  string
-		input []entry
-		want  []vaults.Entry
+		name   string
+		input  []entry
+		groups []struct{ ID, Name string }
+		want   []vaults.Entry
 	}{
 		{
 			"mitigates missing fields",
 			[]entry{
-				{Secret: "secret", Otp: otp{Issuer: "iss-1", Digits: 6, TokenType: "TOTP"}},
-				{Secret: "secret", Otp: otp{Issuer: "iss-2", Digits: 4, TokenType: "HOTP"}},
-				{Secret: "secret", Otp: otp{Issuer: "iss-3", Digits: 0, TokenType: "TOTP", Period: 20}},
-				{Secret: "secret", Otp: otp{Issuer: "iss-4", Digits: 4, TokenType: "TOTP", Algorithm: "SHA256"}},
+				{Secret: "JBSWY3DP", Otp: otp{Issuer: "iss-1", Digits: 6, TokenType: "TOTP"}},
+				{Secret: "JBSWY3DP", Otp: otp{Issuer: "iss-2", Digits: 4, TokenType: "HOTP", Counter: 3}},
+				{Secret: "JBSWY3DP", Otp: otp{Issuer: "iss-3", Digits: 0, TokenType: "TOTP", Period: 20}},
+				{Secret: "JBSWY3DP", Otp: otp{Issuer: "iss-4", Digits: 4, TokenType: "TOTP", Algorithm: "SHA256"}},
 				{Otp: otp{Issuer: "iss-5"}},
 			},
+			nil,
 			[]vaults.Entry{
-				{Issuer: "iss-1", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 30},
-				{Issuer: "iss-3", Digits: 6, Secret: "secret", Type: "TOTP", Algorithm: "SHA1", Period: 20},
-				{Issuer: "iss-4", Digits: 4, Secret: "secret", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
+				{Issuer: "iss-2", Digits: 4, Secret: "JBSWY3DP", Type: "HOTP", Algorithm: "SHA1", Counter: 3},
+				{Issuer: "iss-3", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 20},
+				{Issuer: "iss-4", Digits: 4, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA256", Period: 30},
+			},
+		},
+		{
+			"maps groups to tags",
+			[]entry{
+				{Secret: "JBSWY3DP", GroupID: "id-1", Otp: otp{Issuer: "iss-1", TokenType: "TOTP"}},
+				{Secret: "JBSWY3DP", GroupID: "unknown", Otp: otp{Issuer: "iss-2", TokenType: "TOTP"}},
+			},
+			[]struct{ ID, Name string }{{ID: "id-1", Name: "work"}},
+			[]vaults.Entry{
+				{Issuer: "iss-1", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30, Tags: []string{"work"}},
+				{Issuer: "iss-2", Digits: 6, Secret: "JBSWY3DP", Type: "TOTP", Algorithm: "SHA1", Period: 30},
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			entries := (&twofas{db: tt.input}).Entries()
+			entries := (&twofas{db: tt.input, Groups: tt.groups}).Entries()
 			if !reflect.DeepEqual(entries, tt.want) {
 				t.Fatalf("Entries(): want %#v\nhave %#v", tt.want, entries)
 			}
 		})
 	}
 }
+
+func TestSave(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		password string
+	}{
+		{"encrypted", "testdata/twofas-export-test.2fas", "andcli-test"},
+		{"plain", "testdata/twofas-export-plain.2fas", ""},
+	}
+
+	added := vaults.Entry{
+		Secret: "GEZDGNBVGY3TQOJQ",
+		Issuer: "GitHub",
+		Label:  "alice",
+		Type:   "TOTP",
+		Tags:   []string{"work"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig, err := os.ReadFile(tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filename := filepath.Join(t.TempDir(), "vault.2fas")
+			if err := os.WriteFile(filename, orig, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := Open(filename, []byte(tt.password))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := v.(vaults.Writable)
+			if err := w.Add(added); err != nil {
+				t.Fatal(err)
+			}
+
+			updated := v.Entries()[0]
+			updated.Label = "renamed"
+			if err := w.Update(0, updated); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := w.Update(2, updated); err == nil {
+				t.Fatal("Update() expected error for an invalid index, got none")
+			}
+
+			if err := w.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			backup, err := os.ReadFile(filename + ".bak")
+			if err != nil || !bytes.Equal(backup, orig) {
+				t.Fatalf("Save(): backup does not match the original file: %v", err)
+			}
+
+			v, err = Open(filename, []byte(tt.password))
+			if err != nil {
+				t.Fatalf("Save(): reopen: %v", err)
+			}
+
+			entries := v.Entries()
+			if len(entries) != 2 || entries[0].Label != "renamed" {
+				t.Fatalf("Save(): have %#v", entries)
+			}
+
+			if got := entries[1]; got.Issuer != "GitHub" || got.Label != "alice" || got.Secret != added.Secret || !reflect.DeepEqual(got.Tags, added.Tags) {
+				t.Fatalf("Save(): have %#v, want %#v", got, added)
+			}
+
+			if err := v.(vaults.Writable).Remove(0); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := v.(vaults.Writable).Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			if v, err = Open(filename, []byte(tt.password)); err != nil || len(v.Entries()) != 1 {
+				t.Fatalf("Save(): remove: %v", err)
+			}
+		})
+	}
+}
+
+func TestAdd_invalidType(t *testing.T) {
+	v := &twofas{}
+	e := vaults.Entry{Secret: "JBSWY3DP", Issuer: "iss-1", Type: "MOTP", Pin: "1234"}
+	if err := v.Add(e); err == nil {
+		t.Fatal("Add() expected error for an unsupported type, got none")
+	}
+}
+
+func TestAdd_invalidSecret(t *testing.T) {
+	v := &twofas{}
+	for _, secret := range []string{"not-base32!", "JBSWY3", "===="} {
+		e := vaults.Entry{Secret: secret, Issuer: "iss-1", Type: "TOTP"}
+		if err := v.Add(e); !errors.Is(err, vaults.ErrInvalidSecret) {
+			t.Errorf("Add(%q) error = %v, want %v", secret, err, vaults.ErrInvalidSecret)
+		}
+	}
+
+	if len(v.Entries()) != 0 {
+		t.Errorf("Add(): have entries %v", v.Entries())
+	}
+}
+
+func TestUpdate_account(t *testing.T) {
+	tests := []struct {
+		name           string
+		label, account string
+		wantLabel      string
+		wantAccount    string
+	}{
+		{"label, account kept", "alice", "alice@example.com", "bob", "alice@example.com"},
+		{"label, account copied", "alice", "alice", "bob", "bob"},
+		{"account only", "", "alice", "", "bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := fmt.Sprintf(`[{"name":"GitHub","secret":"GEZDGNBVGY3TQOJQ","otp":{"label":%q,"account":%q,`+
+				`"issuer":"GitHub","digits":6,"period":30,"algorithm":"SHA1","tokenType":"TOTP"}}]`, tt.label, tt.account)
+
+			v := &twofas{}
+			if err := json.Unmarshal([]byte(raw), &v.db); err != nil {
+				t.Fatal(err)
+			}
+
+			e := v.Entries()[0]
+			e.Label = "bob"
+			if err := v.Update(0, e); err != nil {
+				t.Fatal(err)
+			}
+
+			var got struct {
+				Otp struct{ Label, Account string }
+			}
+			if err := json.Unmarshal(v.db[0].raw, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Otp.Label != tt.wantLabel || got.Otp.Account != tt.wantAccount {
+				t.Errorf("Update(): have label %q, account %q, want %q, %q", got.Otp.Label, got.Otp.Account, tt.wantLabel, tt.wantAccount)
+			}
+
+			if label := v.Entries()[0].Label; label != "bob" {
+				t.Errorf("Update(): have entry label %q, want %q", label, "bob")
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	entries := []vaults.Entry{
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "GitHub", Label: "alice", Type: "TOTP", Algorithm: "SHA1", Digits: 6, Period: 30, Tags: []string{"work"}},
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "Counter", Type: "HOTP", Algorithm: "SHA256", Digits: 8, Counter: 3},
+		{Secret: "GEZDGNBVGY3TQOJQ", Issuer: "Steam", Type: "STEAM", Algorithm: "SHA1", Digits: 5, Period: 30},
+	}
+
+	tests := []struct {
+		name      string
+		password  string
+		encrypted bool
+	}{
+		{"encrypted", "andcli-test", true},
+		{"plain", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Export(entries, []byte(tt.password))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var keys map[string]json.RawMessage
+			if err := json.Unmarshal(b, &keys); err != nil {
+				t.Fatal(err)
+			}
+			if ref, ok := keys["reference"]; ok {
+				t.Fatalf("Export(): have reference %s, want none", ref)
+			}
+
+			filename := filepath.Join(t.TempDir(), "export.2fas")
+			if err := os.WriteFile(filename, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if encrypted, _ := IsEncrypted(filename); encrypted != tt.encrypted {
+				t.Fatalf("Export(): encrypted = %v, want %v", encrypted, tt.encrypted)
+			}
+
+			v, err := Open(filename, []byte(tt.password))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := v.Entries(); !reflect.DeepEqual(got, entries) {
+				t.Fatalf("Export(): want %#v\nhave %#v", entries, got)
+			}
+		})
+	}
+
+	_, err := Export([]vaults.Entry{{Issuer: "Mobile", Secret: "JBSWY3DP", Type: "MOTP", Pin: "1234"}}, nil)
+	if want := "Mobile: twofas: " + vaults.ErrInvalidType.Error(); err == nil || err.Error() != want {
+		t.Fatalf("Export() error = %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,149 @@
+package vaults
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ParseURI parses an otpauth:// URI into an entry, following the key uri
+// format (https://github.com/google/google-authenticator/wiki/Key-Uri-Format).
+// The entry is not validated, missing values are left empty.
+func ParseURI(s string) (Entry, error) {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if u.Scheme != "otpauth" {
+		return Entry{}, fmt.Errorf("invalid scheme %q, want otpauth", u.Scheme)
+	}
+
+	q := u.Query()
+	period, _ := strconv.Atoi(q.Get("period"))
+	digits, _ := strconv.Atoi(q.Get("digits"))
+	counter, _ := strconv.Atoi(q.Get("counter"))
+
+	// the label is either "account" or "issuer:account"
+	issuer, account := "", strings.TrimPrefix(u.Path, "/")
+	if parts := strings.SplitN(account, ":", 2); len(parts) == 2 {
+		issuer, account = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	}
+
+	// the issuer parameter takes precedence over the label prefix
+	if v := q.Get("issuer"); v != "" {
+		issuer = v
+	}
+
+	typ := strings.ToUpper(u.Host)
+	if typ == "YAOTP" {
+		typ = YANDEX
+	}
+
+	// motp URIs carry the secret as hex, like the mOTP apps.
+	return Entry{
+		Secret:    q.Get("secret"),
+		Pin:       q.Get("pin"),
+		Issuer:    issuer,
+		Label:     account,
+		Type:      typ,
+		Algorithm: q.Get("algorithm"),
+		Digits:    digits,
+		Period:    period,
+		Counter:   counter,
+		HexSecret: typ == MOTP,
+	}, nil
+}
+
+// ParseURIs parses an otpauth:// or otpauth-migration:// URI into its
+// entries. Like the parsers, it does not validate the entries.
+func ParseURIs(s string) ([]Entry, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "otpauth-migration:") {
+		m, err := ParseMigrationURI(s)
+		if err != nil {
+			return nil, err
+		}
+		return m.Entries, nil
+	}
+
+	e, err := ParseURI(s)
+	if err != nil {
+		return nil, err
+	}
+	return []Entry{e}, nil
+}
+
+// Line is a line of a URI list with its line number, starting at 1.
+type Line struct {
+	Number int
+	Text   string
+}
+
+// Lines returns the non-empty lines of a URI list, skipping comments.
+func Lines(b []byte) []Line {
+	list := make([]Line, 0)
+
+	n := 0
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, Line{n, line})
+	}
+
+	return list
+}
+
+// URI returns the entry as otpauth:// URI, the counterpart to ParseURI.
+func (e Entry) URI() string {
+	secret := e.Secret
+	if strings.ToUpper(e.Type) == MOTP {
+		if s, err := e.motpSecret(); err == nil {
+			secret = s
+		}
+	}
+
+	q := url.Values{}
+	q.Set("secret", secret)
+
+	if e.Issuer != "" {
+		q.Set("issuer", e.Issuer)
+	}
+
+	if e.Algorithm != "" {
+		q.Set("algorithm", strings.ToUpper(e.Algorithm))
+	}
+
+	if e.Digits != 0 {
+		q.Set("digits", strconv.Itoa(e.Digits))
+	}
+
+	if e.IsCounterBased() {
+		q.Set("counter", strconv.Itoa(e.Counter))
+	} else if e.Period != 0 {
+		q.Set("period", strconv.Itoa(e.Period))
+	}
+
+	if e.Pin != "" {
+		q.Set("pin", e.Pin)
+	}
+
+	typ := strings.ToLower(e.Type)
+	if typ == strings.ToLower(YANDEX) {
+		typ = "yaotp"
+	}
+
+	label := e.Label
+	if e.Issuer != "" {
+		label = fmt.Sprintf("%s:%s", e.Issuer, e.Label)
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: typ, Path: "/" + label, RawQuery: q.Encode()}
+	return u.String()
+}
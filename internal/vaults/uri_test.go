@@ -0,0 +1,180 @@
+package vaults
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		want  Entry
+		fails bool
+	}{
+		{
+			"full",
+			"otpauth://totp/ACME%20Co:john@example.com?secret=SECRET&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60",
+			Entry{Secret: "SECRET", Issuer: "ACME Co", Label: "john@example.com", Type: "TOTP", Algorithm: "SHA256", Digits: 8, Period: 60},
+			false,
+		},
+		{
+			"issuer from label",
+			"otpauth://totp/Example:alice@google.com?secret=SECRET",
+			Entry{Secret: "SECRET", Issuer: "Example", Label: "alice@google.com", Type: "TOTP"},
+			false,
+		},
+		{
+			"issuer param wins",
+			"otpauth://totp/Label:alice?secret=SECRET&issuer=Param",
+			Entry{Secret: "SECRET", Issuer: "Param", Label: "alice", Type: "TOTP"},
+			false,
+		},
+		{
+			"account only",
+			"otpauth://totp/alice?secret=SECRET",
+			Entry{Secret: "SECRET", Label: "alice", Type: "TOTP"},
+			false,
+		},
+		{
+			"hotp counter",
+			"otpauth://hotp/alice?secret=SECRET&counter=42",
+			Entry{Secret: "SECRET", Label: "alice", Type: "HOTP", Counter: 42},
+			false,
+		},
+		{
+			"yandex with pin",
+			"otpauth://yaotp/alice?secret=SECRET&pin=1234",
+			Entry{Secret: "SECRET", Pin: "1234", Label: "alice", Type: "YANDEX"},
+			false,
+		},
+		{
+			"motp with hex secret",
+			"otpauth://motp/alice?secret=3132333435363738&pin=1234",
+			Entry{Secret: "3132333435363738", Pin: "1234", Label: "alice", Type: "MOTP", HexSecret: true},
+			false,
+		},
+		{"fails: scheme", "https://example.com/?secret=SECRET", Entry{}, true},
+		{"fails: invalid", "otpauth://totp/%zz", Entry{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseURI(tt.uri)
+			if (err != nil) != tt.fails {
+				t.Fatalf("ParseURI() error = %v, wantErr %v", err, tt.fails)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseURI() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_URI(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry Entry
+		want  string
+	}{
+		{
+			"totp",
+			Entry{Secret: "SECRET", Issuer: "ACME Co", Label: "john@example.com", Type: "TOTP", Algorithm: "sha256", Digits: 8, Period: 60},
+			"otpauth://totp/ACME%20Co:john@example.com?algorithm=SHA256&digits=8&issuer=ACME+Co&period=60&secret=SECRET",
+		},
+		{
+			"hotp",
+			Entry{Secret: "SECRET", Label: "alice", Type: "HOTP", Algorithm: "SHA1", Digits: 6, Counter: 42},
+			"otpauth://hotp/alice?algorithm=SHA1&counter=42&digits=6&secret=SECRET",
+		},
+		{
+			"yandex",
+			Entry{Secret: "SECRET", Pin: "1234", Label: "alice", Type: "YANDEX", Algorithm: "SHA256", Digits: 8, Period: 30},
+			"otpauth://yaotp/alice?algorithm=SHA256&digits=8&period=30&pin=1234&secret=SECRET",
+		},
+		{
+			"motp",
+			Entry{Secret: "3132333435363738", Pin: "1234", Label: "alice", Type: "MOTP", Algorithm: "MD5", Digits: 6, Period: 10, HexSecret: true},
+			"otpauth://motp/alice?algorithm=MD5&digits=6&period=10&pin=1234&secret=3132333435363738",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.entry.URI()
+			if got != tt.want {
+				t.Errorf("Entry.URI() = %v, want %v", got, tt.want)
+			}
+
+			parsed, err := ParseURI(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.entry.Algorithm = strings.ToUpper(tt.entry.Algorithm)
+			if !reflect.DeepEqual(parsed, tt.entry) {
+				t.Errorf("ParseURI(Entry.URI()) = %v, want %v", parsed, tt.entry)
+			}
+		})
+	}
+}
+
+func TestParseURIs(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		want  []Entry
+		fails bool
+	}{
+		{
+			"otpauth",
+			"otpauth://totp/ACME:john?secret=JBSWY3DPEHPK3PXP",
+			[]Entry{{Secret: "JBSWY3DPEHPK3PXP", Issuer: "ACME", Label: "john", Type: TOTP}},
+			false,
+		},
+		{
+			"otpauth-migration",
+			" otpauth-migration://offline?data=Ch8KCkhlbGxvId6tvu8SBWFsaWNlGgRDb3JwMAE4KngB",
+			[]Entry{{Secret: "JBSWY3DPEHPK3PXP", Issuer: "Corp", Label: "alice", Type: HOTP, Counter: 42}},
+			false,
+		},
+		{"fails: invalid migration", "otpauth-migration://offline", nil, true},
+		{"fails: invalid scheme", "https://example.com", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseURIs(tt.uri)
+			if (err != nil) != tt.fails {
+				t.Fatalf("ParseURIs() error = %v, wantErr %v", err, tt.fails)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseURIs() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Line
+	}{
+		{"empty", "", []Line{}},
+		{
+			"keeps line numbers",
+			"# comment\n\notpauth://totp/a\n  \n  otpauth://totp/b  \r\n#otpauth://totp/c\n",
+			[]Line{{3, "otpauth://totp/a"}, {5, "otpauth://totp/b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines([]byte(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,22 +1,44 @@
 package vaults
 
-import "strings"
+import (
+	"errors"
+	"strings"
+)
 
 // Vault is the basic skeleton of a vault implementation.
 type Vault interface{ Entries() []Entry }
 
+// Writable is a vault that can be modified and saved back to its file.
+// Entries are addressed by their index in Entries().
+type Writable interface {
+	Vault
+	Add(e Entry) error
+	Update(i int, e Entry) error
+	Remove(i int) error
+	Save() error
+}
+
+// ErrNoEntry is returned by writable vaults for an invalid entry index.
+var ErrNoEntry = errors.New("entry does not exist")
+
 // Type is an implemented vault type name.
 type Type string
 
 func (t Type) String() string { return string(t) }
 
 const (
-	ANDOTP  Type = "andotp"
-	AEGIS   Type = "aegis"
-	TWOFAS  Type = "twofas"
-	STRATUM Type = "stratum"
-	KEEPASS Type = "keepass"
-	PROTON  Type = "proton"
+	ANDOTP      Type = "andotp"
+	AEGIS       Type = "aegis"
+	TWOFAS      Type = "twofas"
+	STRATUM     Type = "stratum"
+	KEEPASS     Type = "keepass"
+	PROTON      Type = "proton"
+	OTPAUTH     Type = "otpauth"
+	GOOGLE      Type = "google"
+	BITWARDEN   Type = "bitwarden"
+	ENTE        Type = "ente"
+	FREEOTP     Type = "freeotp"
+	FREEOTPPLUS Type = "freeotpplus"
 )
 
 // Returns a list containing the implemented types.
@@ -28,6 +50,12 @@ func Types() []Type {
 		STRATUM,
 		KEEPASS,
 		PROTON,
+		OTPAUTH,
+		GOOGLE,
+		BITWARDEN,
+		ENTE,
+		FREEOTP,
+		FREEOTPPLUS,
 	}
 }
 
@@ -21,6 +21,12 @@ func TestTypes(t *testing.T) {
 				vaults.STRATUM,
 				vaults.KEEPASS,
 				vaults.PROTON,
+				vaults.OTPAUTH,
+				vaults.GOOGLE,
+				vaults.BITWARDEN,
+				vaults.ENTE,
+				vaults.FREEOTP,
+				vaults.FREEOTPPLUS,
 			},
 		},
 	}
@@ -0,0 +1,101 @@
+package vaults
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Patch sets the given fields of the JSON object raw and returns the
+// result. Fields set to nil are removed, all other fields of raw are kept
+// as they are. An empty raw is treated as empty object.
+func Patch(raw json.RawMessage, fields map[string]any) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+	}
+
+	if obj == nil {
+		obj = make(map[string]json.RawMessage)
+	}
+
+	for k, v := range fields {
+		if v == nil {
+			delete(obj, k)
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = b
+	}
+
+	return json.Marshal(obj)
+}
+
+// WriteFile atomically replaces the content of an existing file. The
+// previous content is kept as filename.bak, with the same permissions.
+func WriteFile(filename string, data []byte) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	orig, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := writeAtomic(filename+".bak", orig, fi.Mode().Perm()); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	return writeAtomic(filename, data, fi.Mode().Perm())
+}
+
+// writes data to a temporary file in the same directory and renames it.
+func writeAtomic(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filename)
+}
+
+// NewUUID returns a random version 4 UUID.
+func NewUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,83 @@
+package vaults
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		fields map[string]any
+		want   string
+		fails  bool
+	}{
+		{"sets fields", `{"a":1,"b":"x"}`, map[string]any{"b": "y", "c": []int{1}}, `{"a":1,"b":"y","c":[1]}`, false},
+		{"removes nil fields", `{"a":1,"b":"x"}`, map[string]any{"b": nil}, `{"a":1}`, false},
+		{"keeps nested raw", `{"a":{"x":1}}`, map[string]any{"b": json.RawMessage(`{"y":2}`)}, `{"a":{"x":1},"b":{"y":2}}`, false},
+		{"empty raw", "", map[string]any{"a": 1}, `{"a":1}`, false},
+		{"null raw", "null", map[string]any{"a": 1}, `{"a":1}`, false},
+		{"fails: no object", `[1]`, map[string]any{"a": 1}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Patch(json.RawMessage(tt.raw), tt.fields)
+			if (err != nil) != tt.fails {
+				t.Fatalf("Patch() error = %v, wantErr %v", err, tt.fails)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Patch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "vault.json")
+	if err := os.WriteFile(filename, []byte("original"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFile(filename, []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{filename: "changed", filename + ".bak": "original"} {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("WriteFile(): %s = %q, want %q", name, b, want)
+		}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0o600 {
+			t.Errorf("WriteFile(): %s mode = %v, want 0600", name, fi.Mode().Perm())
+		}
+	}
+
+	files, _ := os.ReadDir(filepath.Dir(filename))
+	if len(files) != 2 {
+		t.Errorf("WriteFile(): left %d files, want 2", len(files))
+	}
+
+	if err := WriteFile(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("WriteFile() expected error for a missing file, got none")
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if a, b := NewUUID(), NewUUID(); !re.MatchString(a) || a == b {
+		t.Errorf("NewUUID() = %s, %s", a, b)
+	}
+}